@@ -0,0 +1,523 @@
+package admin
+
+import (
+	"cs-market/internal/auth"
+	"cs-market/internal/orders"
+	"cs-market/internal/storage"
+	"cs-market/internal/users"
+	"cs-market/internal/wallet"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Security BearerAuth
+// ListUsersHandler godoc
+// @Summary Список пользователей
+// @Description Поиск пользователей по имени или SteamID
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param q query string false "Имя пользователя или SteamID"
+// @Param limit query int false "Количество (до 100)"
+// @Param offset query int false "Смещение"
+// @Success 200 {array} users.User "Пользователи"
+// @Failure 400 {object} response.ErrorResponse "Некорректные параметры"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Router /admin/users [get]
+func ListUsersHandler(c *gin.Context) {
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	query := storage.DB.Order("id DESC").Limit(limit).Offset(offset)
+	if q := c.Query("q"); q != "" {
+		query = query.Where("username ILIKE ? OR steam_id = ?", "%"+q+"%", q)
+	}
+
+	var result []users.User
+	if err := query.Find(&result).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения пользователей"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Security BearerAuth
+// GetUserHandler godoc
+// @Summary Пользователь
+// @Description Получение пользователя по ID
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} users.User "Пользователь"
+// @Failure 400 {object} response.ErrorResponse "Некорректный ID пользователя"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id} [get]
+func GetUserHandler(c *gin.Context) {
+	user, ok := targetUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// @Security BearerAuth
+// BanUserHandler godoc
+// @Summary Блокировка пользователя
+// @Description Ограничивает вход, торговлю или вывод средств. Блокировка входа завершает все сессии пользователя. Нельзя блокировать себя и сотрудников своей роли или старше
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param input body BanRequest true "Область, причина и срок"
+// @Success 200 {object} response.SuccessResponse "Пользователь заблокирован"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id}/ban [post]
+func BanUserHandler(c *gin.Context) {
+	var input BanRequest
+	if err := c.ShouldBindJSON(&input); err != nil || (input.ExpiresAt != nil && input.ExpiresAt.Before(time.Now())) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	details := fmt.Sprintf(`{"scope": %q}`, input.Scope)
+	if input.ExpiresAt != nil {
+		details = fmt.Sprintf(`{"scope": %q, "expires_at": %q}`, input.Scope, input.ExpiresAt.Format(time.RFC3339))
+	}
+	performAction(c, ActionBan, input.Reason, details, func(tx *gorm.DB, adminUser, target users.User) error {
+		err := tx.Create(&users.Ban{
+			UserID:    target.ID,
+			Scope:     input.Scope,
+			Reason:    input.Reason,
+			ExpiresAt: input.ExpiresAt,
+			IssuedBy:  adminUser.ID,
+		}).Error
+		if err != nil || input.Scope != users.BanScopeLogin {
+			return err
+		}
+		return auth.RevokeUserTokens(tx, target.ID)
+	}, "Пользователь заблокирован")
+}
+
+// @Security BearerAuth
+// UnbanUserHandler godoc
+// @Summary Разблокировка пользователя
+// @Description Снимает действующие блокировки пользователя в указанной области или все. Снять блокировку с себя или сотрудника своей роли или старше нельзя
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param input body UnbanRequest true "Область и причина"
+// @Success 200 {object} response.SuccessResponse "Пользователь разблокирован"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id}/unban [post]
+func UnbanUserHandler(c *gin.Context) {
+	var input UnbanRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	details := ""
+	if input.Scope != "" {
+		details = fmt.Sprintf(`{"scope": %q}`, input.Scope)
+	}
+	performAction(c, ActionUnban, input.Reason, details, func(tx *gorm.DB, adminUser, target users.User) error {
+		query := tx.Model(&users.Ban{}).Where("user_id = ? AND lifted_at IS NULL", target.ID)
+		if input.Scope != "" {
+			query = query.Where("scope = ?", input.Scope)
+		}
+		return query.Updates(map[string]interface{}{"lifted_at": time.Now(), "lifted_by": adminUser.ID}).Error
+	}, "Пользователь разблокирован")
+}
+
+// @Security BearerAuth
+// GetUserBansHandler godoc
+// @Summary Блокировки пользователя
+// @Description История блокировок пользователя, включая снятые и истёкшие
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {array} users.Ban "Блокировки"
+// @Failure 400 {object} response.ErrorResponse "Некорректный ID пользователя"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id}/bans [get]
+func GetUserBansHandler(c *gin.Context) {
+	user, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	var result []users.Ban
+	if err := storage.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&result).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения блокировок"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Security BearerAuth
+// ForceLogoutHandler godoc
+// @Summary Принудительный выход
+// @Description Завершает все сессии пользователя. Нельзя применить к себе и сотрудникам своей роли или старше
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param input body ReasonRequest true "Причина"
+// @Success 200 {object} response.SuccessResponse "Сессии пользователя завершены"
+// @Failure 400 {object} response.ErrorResponse "Не указана причина"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id}/logout [post]
+func ForceLogoutHandler(c *gin.Context) {
+	var input ReasonRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не указана причина"})
+		return
+	}
+
+	performAction(c, ActionForceLogout, input.Reason, "", func(tx *gorm.DB, adminUser, target users.User) error {
+		return auth.RevokeUserTokens(tx, target.ID)
+	}, "Сессии пользователя завершены")
+}
+
+// @Security BearerAuth
+// SetRoleHandler godoc
+// @Summary Назначение роли
+// @Description Назначает пользователю роль user, support или moderator и завершает его сессии, чтобы новые токены получили новую роль. Роль admin выдаётся только через ADMIN_STEAM_IDS
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param input body SetRoleRequest true "Роль и причина"
+// @Success 200 {object} response.SuccessResponse "Роль назначена"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id}/role [put]
+func SetRoleHandler(c *gin.Context) {
+	var input SetRoleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	details := fmt.Sprintf(`{"role": %q}`, input.Role)
+	performAction(c, ActionSetRole, input.Reason, details, func(tx *gorm.DB, adminUser, target users.User) error {
+		if err := tx.Model(&target).Update("role", input.Role).Error; err != nil {
+			return err
+		}
+		return auth.RevokeUserTokens(tx, target.ID)
+	}, "Роль назначена")
+}
+
+// @Security BearerAuth
+// GetUserOrdersHandler godoc
+// @Summary Заказы пользователя
+// @Description Покупки и продажи пользователя
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {array} orders.Order "Заказы"
+// @Failure 400 {object} response.ErrorResponse "Некорректный ID пользователя"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id}/orders [get]
+func GetUserOrdersHandler(c *gin.Context) {
+	user, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	var result []orders.Order
+	err := storage.DB.Where("buyer_id = ? OR seller_id = ?", user.ID, user.ID).
+		Order("created_at DESC").Find(&result).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения заказов"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Security BearerAuth
+// DisputeOrderHandler godoc
+// @Summary Спор по заказу
+// @Description Передаёт заказ под защитой обмена в спор с возвратом средств покупателю. Нужен, когда Steam откатил обмен, который площадка не проверяет сама, например по ручному заказу. Заказ, в котором сотрудник участвует сам, передать нельзя. В журнал аудита попадает с продавцом как целью
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заказа"
+// @Param input body ReasonRequest true "Причина"
+// @Success 200 {object} orders.Order "Заказ"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Заказ не найден"
+// @Failure 409 {object} response.ErrorResponse "Заказ не под защитой обмена"
+// @Router /admin/orders/{id}/dispute [post]
+func DisputeOrderHandler(c *gin.Context) {
+	var input ReasonRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID заказа"})
+		return
+	}
+	var adminUser users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&adminUser).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	var order orders.Order
+	if err := storage.DB.First(&order, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Заказ не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения заказа"})
+		}
+		return
+	}
+	if order.BuyerID == adminUser.ID || order.SellerID == adminUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Действие над собой запрещено"})
+		return
+	}
+	if order.Status != orders.StatusProtected {
+		c.JSON(http.StatusConflict, gin.H{"error": "Заказ не под защитой обмена"})
+		return
+	}
+
+	var disputed *orders.Order
+	err = storage.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if disputed, err = orders.Transition(tx, order.ID, orders.StatusDisputed); err != nil {
+			return err
+		}
+		return tx.Create(&AuditLog{
+			AdminID:      adminUser.ID,
+			Action:       ActionDisputeOrder,
+			TargetUserID: order.SellerID,
+			Reason:       input.Reason,
+			Details:      fmt.Sprintf(`{"order_id": %d}`, order.ID),
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, orders.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Заказ не под защитой обмена"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка выполнения действия"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, disputed)
+}
+
+// @Security BearerAuth
+// GetUserWalletHandler godoc
+// @Summary Кошелёк пользователя
+// @Description Баланс и операции по кошельку пользователя
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param limit query int false "Количество операций (до 100)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} UserWalletResponse "Кошелёк"
+// @Failure 400 {object} response.ErrorResponse "Некорректный ID пользователя"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id}/wallet [get]
+func GetUserWalletHandler(c *gin.Context) {
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+	user, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	account, err := wallet.UserAccount(storage.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения кошелька"})
+		return
+	}
+	transactions, err := wallet.UserTransactions(storage.DB, user.ID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения операций"})
+		return
+	}
+
+	c.JSON(http.StatusOK, UserWalletResponse{
+		Balance:      wallet.ToRubles(account.Balance),
+		Currency:     wallet.Currency,
+		Transactions: transactions,
+	})
+}
+
+// @Security BearerAuth
+// AdjustBalanceHandler godoc
+// @Summary Корректировка баланса
+// @Description Ручное начисление или списание средств с обязательной причиной. Свой баланс и баланс других администраторов изменить нельзя
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param input body AdjustBalanceRequest true "Сумма и причина"
+// @Success 200 {object} response.SuccessResponse "Баланс изменён"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 402 {object} response.ErrorResponse "Недостаточно средств"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /admin/users/{id}/wallet/adjust [post]
+func AdjustBalanceHandler(c *gin.Context) {
+	var input AdjustBalanceRequest
+	if err := c.ShouldBindJSON(&input); err != nil || wallet.FromRubles(input.Amount) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	amount := wallet.FromRubles(input.Amount)
+	details := fmt.Sprintf(`{"amount": %d}`, amount)
+	performAction(c, ActionAdjustBalance, input.Reason, details, func(tx *gorm.DB, adminUser, target users.User) error {
+		return wallet.Adjust(tx, target.ID, amount, "admin:"+c.GetString("user_id"), input.Reason)
+	}, "Баланс изменён")
+}
+
+// @Security BearerAuth
+// GetAuditLogHandler godoc
+// @Summary Журнал аудита
+// @Description Действия администраторов, новые первыми
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param user_id query int false "ID пользователя, над которым выполнялось действие"
+// @Param limit query int false "Количество (до 100)"
+// @Param offset query int false "Смещение"
+// @Success 200 {array} AuditLog "Записи журнала"
+// @Failure 403 {object} response.ErrorResponse "Недостаточно прав"
+// @Router /admin/audit [get]
+func GetAuditLogHandler(c *gin.Context) {
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	query := storage.DB.Order("id DESC").Limit(limit).Offset(offset)
+	if target := c.Query("user_id"); target != "" {
+		query = query.Where("target_user_id = ?", target)
+	}
+
+	var result []AuditLog
+	if err := query.Find(&result).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения журнала"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// performAction выполняет действие над пользователем и записывает его в журнал аудита в одной транзакции.
+// Действовать над собой и над сотрудниками своей роли или старше нельзя
+func performAction(c *gin.Context, action, reason, details string, apply func(tx *gorm.DB, adminUser, target users.User) error, message string) {
+	var adminUser users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&adminUser).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+	target, ok := targetUser(c)
+	if !ok {
+		return
+	}
+	if target.ID == adminUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Действие над собой запрещено"})
+		return
+	}
+	if users.RoleRank(target.Role) >= users.RoleRank(adminUser.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав для действия над этим пользователем"})
+		return
+	}
+
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := apply(tx, adminUser, target); err != nil {
+			return err
+		}
+		return tx.Create(&AuditLog{
+			AdminID:      adminUser.ID,
+			Action:       action,
+			TargetUserID: target.ID,
+			Reason:       reason,
+			Details:      details,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, wallet.ErrInsufficientFunds) {
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": "Недостаточно средств"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка выполнения действия"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// targetUser загружает пользователя из параметра id. Строку нельзя передавать в First напрямую:
+// нечисловое значение GORM подставит в запрос как условие WHERE
+func targetUser(c *gin.Context) (users.User, bool) {
+	var user users.User
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пользователя"})
+		return user, false
+	}
+	if err := storage.DB.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения пользователя"})
+		}
+		return user, false
+	}
+	return user, true
+}
+
+func pagination(c *gin.Context) (int, int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный limit"})
+		return 0, 0, false
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный offset"})
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
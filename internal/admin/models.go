@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"cs-market/internal/wallet"
+	"time"
+)
+
+// Действия администраторов, попадающие в журнал аудита
+const (
+	ActionBan           = "ban"
+	ActionUnban         = "unban"
+	ActionForceLogout   = "force_logout"
+	ActionAdjustBalance = "adjust_balance"
+	ActionSetRole       = "set_role"
+	ActionDisputeOrder  = "dispute_order"
+)
+
+// AuditLog - запись журнала действий администраторов
+type AuditLog struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	AdminID      uint      `json:"admin_id" gorm:"not null;index"`
+	Action       string    `json:"action" gorm:"not null;index"`
+	TargetUserID uint      `json:"target_user_id" gorm:"not null;index"`
+	Reason       string    `json:"reason" gorm:"not null"`
+	Details      string    `json:"details"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ReasonRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type BanRequest struct {
+	Scope  string `json:"scope" binding:"required,oneof=login trading withdrawals"`
+	Reason string `json:"reason" binding:"required"`
+	// ExpiresAt - окончание блокировки, без него блокировка бессрочная
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UnbanRequest struct {
+	// Scope - снимаемая область, без неё снимаются все блокировки
+	Scope  string `json:"scope" binding:"omitempty,oneof=login trading withdrawals"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+type SetRoleRequest struct {
+	// Role - новая роль, admin назначается только через ADMIN_STEAM_IDS
+	Role   string `json:"role" binding:"required,oneof=user support moderator"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+type AdjustBalanceRequest struct {
+	// Amount - сумма в рублях: положительная начисляет, отрицательная списывает
+	Amount float64 `json:"amount" binding:"required"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+type UserWalletResponse struct {
+	Balance      float64                      `json:"balance"`
+	Currency     string                       `json:"currency"`
+	Transactions []wallet.TransactionResponse `json:"transactions"`
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// Типы токенов
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+const (
+	defaultIssuer   = "cs-market"
+	defaultAudience = "cs-market"
+)
+
+var (
+	ErrWrongTokenType = errors.New("неверный тип токена")
+	ErrWrongIssuer    = errors.New("неверный издатель токена")
+	ErrWrongAudience  = errors.New("неверная аудитория токена")
+)
+
+// Claims - содержимое токенов. Subject - SteamID пользователя
+type Claims struct {
+	jwt.StandardClaims
+	Type      string   `json:"typ"`
+	SessionID string   `json:"sid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+// Issuer - издатель токенов (JWT_ISSUER)
+func Issuer() string {
+	if iss := os.Getenv("JWT_ISSUER"); iss != "" {
+		return iss
+	}
+	return defaultIssuer
+}
+
+// Audience - аудитория токенов доступа (JWT_AUDIENCE)
+func Audience() string {
+	if aud := os.Getenv("JWT_AUDIENCE"); aud != "" {
+		return aud
+	}
+	return defaultAudience
+}
+
+// validate проверяет издателя, аудиторию и тип токена. Сроки проверяет jwt.Parse
+func (c *Claims) validate(tokenType string) error {
+	if c.Type != tokenType {
+		return ErrWrongTokenType
+	}
+	if !c.VerifyIssuer(Issuer(), true) {
+		return ErrWrongIssuer
+	}
+	if !c.VerifyAudience(Audience(), true) {
+		return ErrWrongAudience
+	}
+	if c.Subject == "" {
+		return errors.New("токен без sub")
+	}
+	return nil
+}
+
+// HasRole проверяет роль пользователя из токена доступа текущего запроса
+func HasRole(c *gin.Context, role string) bool {
+	for _, r := range c.GetStringSlice("roles") {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
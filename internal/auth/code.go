@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"cs-market/internal/users"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	authCodeTTL    = time.Minute
+	stateCookie    = "auth_state"
+	stateCookieTTL = 10 * time.Minute
+)
+
+var ErrInvalidAuthCode = errors.New("неверный или использованный код авторизации")
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// createAuthCode выдаёт короткоживущий одноразовый код для обмена на токены
+func createAuthCode(db *gorm.DB, userID uint) (string, error) {
+	code := newID()
+	record := AuthCode{
+		CodeHash:  hashCode(code),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(authCodeTTL),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// redeemAuthCode помечает код использованным и возвращает его владельца.
+// Код принимается только один раз и только до истечения срока
+func redeemAuthCode(db *gorm.DB, code string) (users.User, error) {
+	var user users.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		hash := hashCode(code)
+		result := tx.Model(&AuthCode{}).
+			Where("code_hash = ? AND used_at IS NULL AND expires_at > ?", hash, now).
+			Update("used_at", &now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidAuthCode
+		}
+
+		var record AuthCode
+		if err := tx.Where("code_hash = ?", hash).First(&record).Error; err != nil {
+			return err
+		}
+		return tx.First(&user, record.UserID).Error
+	})
+	return user, err
+}
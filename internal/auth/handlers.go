@@ -1,52 +1,76 @@
 package auth
 
 import (
+	"crypto/subtle"
+	"cs-market/internal/steamapi"
 	"cs-market/internal/storage"
 	"cs-market/internal/users"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
-	"github.com/markbates/goth"
 	"github.com/markbates/goth/providers/steam"
 	"gorm.io/gorm"
 )
 
+var steamKey, callbackURL string
+
+// jwtSecretRefresh - ключ подписи refresh-токенов (JWT_KEY_REFRESH). Читается в InitAuth, после загрузки .env
+var jwtSecretRefresh []byte
+
 func InitAuth() {
-	steamKey := os.Getenv("STEAM_API_KEY")
-	callbackURL := os.Getenv("CALLBACK_URL")
+	steamKey = os.Getenv("STEAM_API_KEY")
+	callbackURL = os.Getenv("CALLBACK_URL")
+	jwtSecretRefresh = []byte(os.Getenv("JWT_KEY_REFRESH"))
+	if len(jwtSecretRefresh) == 0 {
+		// С пустым ключом HMAC-подпись может подделать любой
+		log.Fatal("Не задан JWT_KEY_REFRESH")
+	}
 
-	log.Printf("Initializing Steam auth with key: %s and callback: %s", steamKey, callbackURL)
+	log.Printf("Initializing Steam auth with callback: %s", callbackURL)
 
-	goth.UseProviders(
-		steam.New(steamKey, callbackURL),
-	)
+	if err := initKeys(); err != nil {
+		log.Fatal("Ошибка загрузки ключей JWT: ", err)
+	}
+}
+
+// steamProvider создаёт провайдера Steam, адрес возврата которого содержит state.
+// Steam OpenID не поддерживает state сам, поэтому он передаётся в openid.return_to
+func steamProvider(state string) (*steam.Provider, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("state", state)
+	u.RawQuery = q.Encode()
+	return steam.New(steamKey, u.String()), nil
 }
 
 // SteamLoginHandler godoc
 // @Summary Авторизация через Steam
-// @Description Авторизация через Steam и получение токенов доступа (для теста требуется подключение в steam хоста с https)
+// @Description Перенаправляет на вход через Steam. Параметр state сохраняется в куки и проверяется при возврате (для теста требуется подключение в steam хоста с https)
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Success 303 {string} string "Redirect URL"
+// @Success 307 {string} string "Redirect URL"
 // @Failure 400 {object} response.ErrorResponse "Ошибка начала авторизации Steam"
 // @Router /auth/steam [get]
 func SteamLoginHandler(c *gin.Context) {
-	provider, err := goth.GetProvider("steam")
+	state := newID()
+	provider, err := steamProvider(state)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка получения провайдера Steam"})
 		return
 	}
 
-	session, err := provider.BeginAuth("")
+	session, err := provider.BeginAuth(state)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка начала авторизации Steam"})
 		return
@@ -58,27 +82,38 @@ func SteamLoginHandler(c *gin.Context) {
 		return
 	}
 
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(stateCookie, state, int(stateCookieTTL.Seconds()), "/auth", "", false, true)
 	c.Redirect(http.StatusTemporaryRedirect, authURL)
 }
 
 // SteamCallbackHandler godoc
 // @Summary Обработчик коллбэка после авторизации через Steam
-// @Description Обработчик коллбэка после авторизации через Steam и получение токенов доступа
+// @Description Проверяет state и перенаправляет на фронтенд с одноразовым кодом, который обменивается на токены через /auth/exchange
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Success 303 {string} string "Ссылка с указанием токенов доступа"
+// @Param state query string true "state, выданный при входе"
+// @Success 303 {string} string "Ссылка с одноразовым кодом авторизации"
 // @Failure 400 {object} response.ErrorResponse "Ошибка авторизации Steam"
-// @Failure 500 {object} response.ErrorResponse "Ошибка генерации токенов" "Ошибка входа в систему"
+// @Failure 403 {object} response.ErrorResponse "Аккаунт заблокирован или не прошёл проверку"
+// @Failure 500 {object} response.ErrorResponse "Ошибка входа в систему"
 // @Router /auth/steam/callback [get]
 func SteamCallbackHandler(c *gin.Context) {
-	provider, err := goth.GetProvider("steam")
+	state, err := c.Cookie(stateCookie)
+	c.SetCookie(stateCookie, "", -1, "/auth", "", false, true)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный параметр state"})
+		return
+	}
+
+	provider, err := steamProvider(state)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка получения провайдера Steam"})
 		return
 	}
 
-	session, err := provider.BeginAuth("")
+	session, err := provider.BeginAuth(state)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка начала авторизации Steam"})
 		return
@@ -90,13 +125,17 @@ func SteamCallbackHandler(c *gin.Context) {
 		return
 	}
 
-	steamUser, err := provider.FetchUser(session)
+	steamID := session.(*steam.Session).SteamID
+	client := steamapi.Default()
+
+	summary, err := client.PlayerSummary(c.Request.Context(), steamID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка получения данных Steam"})
+		log.Println(err)
 		return
 	}
 
-	steamLvl, err := GetUserSteamLVL(steamUser.UserID)
+	steamLvl, err := client.SteamLevel(c.Request.Context(), steamID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка получения данных Steam"})
 		log.Println(err)
@@ -104,15 +143,16 @@ func SteamCallbackHandler(c *gin.Context) {
 	}
 
 	var user users.User
-	result := storage.DB.Where("steam_id = ?", steamUser.UserID).First(&user)
+	result := storage.DB.Where("steam_id = ?", steamID).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			// Создание нового пользователя, если его нет
 			user = users.User{
-				SteamID:   steamUser.UserID,
-				Username:  steamUser.NickName,
-				AvatarURL: steamUser.AvatarURL,
+				SteamID:   steamID,
+				Username:  summary.PersonaName,
+				AvatarURL: summary.AvatarFull,
 				SteamLVL:  steamLvl,
+				Role:      users.InitialRole(steamID),
 			}
 			storage.DB.Create(&user)
 		} else {
@@ -122,44 +162,139 @@ func SteamCallbackHandler(c *gin.Context) {
 	} else {
 		// Обновление данных пользователя при повторном входе
 		storage.DB.Model(&user).Updates(users.User{
-			Username:  steamUser.NickName,
-			AvatarURL: steamUser.AvatarURL,
+			Username:  summary.PersonaName,
+			AvatarURL: summary.AvatarFull,
 			SteamLVL:  steamLvl,
 		})
 	}
 
-	accessToken, refreshToken, err := GenerateTokensJWT(user.SteamID)
+	ban, err := users.ActiveBan(storage.DB, user.ID, users.BanScopeLogin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка входа в систему"})
+		return
+	}
+	if ban != nil {
+		abortRestricted(c, ban)
+		return
+	}
+
+	violations, err := refreshSteamTrust(c.Request.Context(), storage.DB, &user, summary)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка получения данных Steam"})
+		log.Println(err)
+		return
+	}
+	for _, v := range violations {
+		if v.Action == users.TrustActionBlock {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Аккаунт Steam не прошёл проверку", "violations": violations})
+			return
+		}
+	}
+
+	code, err := createAuthCode(storage.DB, user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токена"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка входа в систему"})
 		return
 	}
 
 	frontUrl := os.Getenv("FRONT_URL")
-	redirectURL := frontUrl + "/auth?" +
-		"access_token=" + accessToken + "&refresh_token=" + refreshToken
+	redirectURL := frontUrl + "/auth?code=" + url.QueryEscape(code)
 
+	c.Header("Referrer-Policy", "no-referrer")
 	c.Redirect(http.StatusSeeOther, redirectURL)
 }
 
-var jwtSecret = []byte(os.Getenv("JWT_KEY"))
+// ExchangeCodeHandler godoc
+// @Summary Обмен кода авторизации на токены
+// @Description Обменивает одноразовый код из редиректа после входа на токен доступа и устанавливает HttpOnly куки refresh_token. Код действует одну минуту
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body ExchangeRequest true "Код авторизации"
+// @Success 200 {object} response.TokenResponse "Токен доступа"
+// @Failure 400 {object} response.ErrorResponse "Не указан код"
+// @Failure 401 {object} response.ErrorResponse "Неверный код авторизации"
+// @Failure 403 {object} response.ErrorResponse "Аккаунт заблокирован"
+// @Failure 500 {object} response.ErrorResponse "Ошибка генерации токенов"
+// @Router /auth/exchange [post]
+func ExchangeCodeHandler(c *gin.Context) {
+	var input ExchangeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не указан код"})
+		return
+	}
+
+	user, err := redeemAuthCode(storage.DB, input.Code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAuthCode) || errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный код авторизации"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токенов"})
+		return
+	}
+	ban, err := users.ActiveBan(storage.DB, user.ID, users.BanScopeLogin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токенов"})
+		return
+	}
+	if ban != nil {
+		abortRestricted(c, ban)
+		return
+	}
+
+	accessToken, refreshToken, err := issueTokens(storage.DB, user, "", c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токенов"})
+		return
+	}
 
-var jwtSecretRefresh = []byte(os.Getenv("JWT_KEY_REFRESH"))
+	c.SetCookie("refresh_token", refreshToken, int(refreshTokenTTL.Seconds()), "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{"access_token": accessToken})
+}
+
+// GenerateTokensJWT подписывает токен доступа текущим асимметричным ключом с указанием kid,
+// refresh-токен остаётся внутренним и подписывается секретом JWT_KEY_REFRESH
+func GenerateTokensJWT(stramID string, roles []string, jti, family string) (string, string, error) {
+	key, err := keys.signing()
+	if err != nil {
+		return "", "", err
+	}
 
-func GenerateTokensJWT(stramID string) (string, string, error) {
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": stramID,
-		// "exp":     time.Now().Add(15 * time.Minute).Unix(),
-		"exp": time.Now().Add(300 * time.Hour).Unix(),
+	now := time.Now()
+	accessToken := jwt.NewWithClaims(key.Method, Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   stramID,
+			Issuer:    Issuer(),
+			Audience:  Audience(),
+			Id:        newID(),
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(accessTokenTTL).Unix(),
+		},
+		Type:      TokenTypeAccess,
+		SessionID: family,
+		Roles:     roles,
 	})
+	accessToken.Header["kid"] = key.ID
 
-	accessTokenString, err := accessToken.SignedString(jwtSecret)
+	accessTokenString, err := accessToken.SignedString(key.Private)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": stramID,
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
+	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   stramID,
+			Issuer:    Issuer(),
+			Audience:  Audience(),
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(refreshTokenTTL).Unix(),
+		},
+		Type:      TokenTypeRefresh,
+		SessionID: family,
 	})
 
 	refreshTokenString, err := refreshToken.SignedString(jwtSecretRefresh)
@@ -170,28 +305,59 @@ func GenerateTokensJWT(stramID string) (string, string, error) {
 	return accessTokenString, refreshTokenString, nil
 }
 
-func ValidToken(tokenStr string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// ValidToken проверяет токен доступа открытым ключом, указанным в заголовке kid.
+// Refresh-токен в качестве токена доступа не принимается
+func ValidToken(tokenStr string) (*Claims, error) {
+	var claims Claims
+	token, err := jwt.ParseWithClaims(tokenStr, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key id: %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
+		return key.Public, nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if err := claims.validate(TokenTypeAccess); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+// ValidRefreshToken проверяет подпись refresh-токена его собственным ключом
+func ValidRefreshToken(tokenStr string) (*Claims, error) {
+	var claims Claims
+	token, err := jwt.ParseWithClaims(tokenStr, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtSecretRefresh, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	if err := claims.validate(TokenTypeRefresh); err != nil {
+		return nil, err
+	}
+	return &claims, nil
 }
 
 // RefreshTokenHandler godoc
 // @Summary Обновление токена доступа
-// @Description Обновление токена доступа с помощью refresh_token
+// @Description Обновление токена доступа с помощью refresh_token. Refresh-токен одноразовый: в ответ выдаётся новый, повторное использование отзывает всю сессию
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -207,23 +373,78 @@ func RefreshTokenHandler(c *gin.Context) {
 		return
 	}
 
-	claims, err := ValidToken(refreshToken)
+	claims, err := ValidRefreshToken(refreshToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный refresh_token"})
 		return
 	}
-
-	accsessToken, refreshToken, err := GenerateTokensJWT(claims["user_id"].(string))
+	accsessToken, refreshToken, err := rotateRefreshToken(claims.Id, c)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrTokenRevoked) || errors.Is(err, ErrTokenReused) {
+			c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный refresh_token"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токенов"})
 		return
 	}
 
-	c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "/", "", false, true)
+	c.SetCookie("refresh_token", refreshToken, int(refreshTokenTTL.Seconds()), "/", "", false, true)
 
 	c.JSON(http.StatusOK, gin.H{"access_token": accsessToken})
 }
 
+// LogoutHandler godoc
+// @Summary Выход из системы
+// @Description Отзывает текущую сессию: все refresh-токены цепочки, к которой относится токен из куки
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.SuccessResponse "Выход выполнен"
+// @Router /auth/logout [post]
+func LogoutHandler(c *gin.Context) {
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil {
+		if claims, err := ValidRefreshToken(refreshToken); err == nil {
+			if claims.SessionID != "" {
+				if err := revokeFamily(storage.DB, claims.SessionID); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка выхода из системы"})
+					return
+				}
+			}
+		}
+	}
+
+	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{"message": "Выход выполнен"})
+}
+
+// @Security BearerAuth
+// LogoutAllHandler godoc
+// @Summary Выход со всех устройств
+// @Description Отзывает все refresh-токены пользователя
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.SuccessResponse "Выход выполнен на всех устройствах"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка выхода из системы"
+// @Router /auth/logout-all [post]
+func LogoutAllHandler(c *gin.Context) {
+	var user users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	if err := RevokeUserTokens(storage.DB, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка выхода из системы"})
+		return
+	}
+
+	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{"message": "Выход выполнен на всех устройствах"})
+}
+
 func TokenProv(c *gin.Context) {
 	userID := c.GetString("user_id")
 
@@ -251,33 +472,3 @@ func VerifyTokenHandler(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"valid": true, "user_id": userID})
 }
-
-func GetUserSteamLVL(steam_id string) (int, error) {
-	url := fmt.Sprintf("http://api.steampowered.com/IPlayerService/GetSteamLevel/v1/?key=%s&steamid=%s", os.Getenv("STEAM_API_KEY"), steam_id)
-	log.Println(url)
-	resp, err := http.Get(url)
-	if err != nil {
-		fmt.Println("Ошибка при выполнении запроса:", err)
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Ошибка при чтении ответа:", err)
-		return 0, err
-	}
-
-	var result struct {
-		Response struct {
-			PlayerLevel int `json:"player_level"`
-		} `json:"response"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		fmt.Println("Ошибка при декодировании JSON:", err)
-		return 0, err
-	}
-
-	return result.Response.PlayerLevel, nil
-}
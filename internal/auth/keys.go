@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	defaultKeysDir       = "keys"
+	defaultKeyRotation   = 30 * 24 * time.Hour
+	keyDirReloadInterval = time.Minute
+	privateKeyFileSuffix = ".pem"
+	publicKeyFileSuffix  = ".pub.pem"
+	keyAlgEdDSA          = "EdDSA"
+	keyAlgRS256          = "RS256"
+	rsaKeyBits           = 2048
+)
+
+// signingKey - ключ подписи токенов доступа. У ключей только для проверки нет Private
+type signingKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.PrivateKey
+	Public    crypto.PublicKey
+	CreatedAt time.Time
+}
+
+// keyRing хранит текущий ключ подписи и все действующие ключи проверки.
+// Ключи загружаются из каталога JWT_KEYS_DIR: <kid>.pem - закрытый ключ (PKCS#8),
+// <kid>.pub.pem - открытый ключ другого сервиса или уже выведенного из подписи ключа
+type keyRing struct {
+	mu      sync.RWMutex
+	dir     string
+	keys    map[string]*signingKey
+	current *signingKey
+}
+
+var keys = &keyRing{keys: make(map[string]*signingKey)}
+
+// keyRotation - период смены ключа подписи (JWT_KEY_ROTATION)
+func keyRotation() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("JWT_KEY_ROTATION")); err == nil && d > 0 {
+		return d
+	}
+	return defaultKeyRotation
+}
+
+// keyAlg - алгоритм новых ключей (JWT_KEY_ALG): EdDSA или RS256
+func keyAlg() string {
+	if os.Getenv("JWT_KEY_ALG") == keyAlgRS256 {
+		return keyAlgRS256
+	}
+	return keyAlgEdDSA
+}
+
+// load перечитывает каталог ключей. Текущим ключом подписи становится самый новый закрытый ключ
+func (r *keyRing) load() error {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]*signingKey)
+	var current *signingKey
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, privateKeyFileSuffix) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join(r.dir, name))
+		if err != nil {
+			return err
+		}
+
+		var key *signingKey
+		if strings.HasSuffix(name, publicKeyFileSuffix) {
+			key, err = parsePublicKey(strings.TrimSuffix(name, publicKeyFileSuffix), data)
+		} else {
+			key, err = parsePrivateKey(strings.TrimSuffix(name, privateKeyFileSuffix), data)
+		}
+		if err != nil {
+			return fmt.Errorf("ключ %s: %w", name, err)
+		}
+		key.CreatedAt = info.ModTime()
+		loaded[key.ID] = key
+
+		if key.Private != nil && (current == nil || key.CreatedAt.After(current.CreatedAt)) {
+			current = key
+		}
+	}
+
+	// Ключи, которыми перестали подписывать, нужны только пока живут выданные ими токены
+	for id, key := range loaded {
+		if key != current && key.Private != nil && time.Since(key.CreatedAt) > keyRotation()+accessTokenTTL {
+			delete(loaded, id)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = loaded
+	r.current = current
+	return nil
+}
+
+// rotate создаёт новый закрытый ключ в каталоге и делает его текущим
+func (r *keyRing) rotate() error {
+	var private crypto.PrivateKey
+	var err error
+	if keyAlg() == keyAlgRS256 {
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	} else {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+	kid := time.Now().UTC().Format("20060102T150405Z")
+	path := filepath.Join(r.dir, kid+privateKeyFileSuffix)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	log.Println("Создан новый ключ подписи JWT:", kid)
+	return r.load()
+}
+
+func (r *keyRing) signing() (*signingKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.current == nil {
+		return nil, errors.New("нет ключа подписи")
+	}
+	return r.current, nil
+}
+
+func (r *keyRing) lookup(kid string) (*signingKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[kid]
+	return key, ok
+}
+
+func (r *keyRing) all() []*signingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]*signingKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		list = append(list, key)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func parsePrivateKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("не PEM")
+	}
+
+	var private crypto.PrivateKey
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := private.(type) {
+	case ed25519.PrivateKey:
+		return &signingKey{ID: kid, Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	case *rsa.PrivateKey:
+		return &signingKey{ID: kid, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип ключа %T", private)
+	}
+}
+
+func parsePublicKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("не PEM")
+	}
+
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := public.(type) {
+	case ed25519.PublicKey:
+		return &signingKey{ID: kid, Method: jwt.SigningMethodEdDSA, Public: k}, nil
+	case *rsa.PublicKey:
+		return &signingKey{ID: kid, Method: jwt.SigningMethodRS256, Public: k}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип ключа %T", public)
+	}
+}
+
+// initKeys загружает ключи подписи, при их отсутствии создаёт первый,
+// и запускает плановую ротацию
+func initKeys() error {
+	keys.dir = os.Getenv("JWT_KEYS_DIR")
+	if keys.dir == "" {
+		keys.dir = defaultKeysDir
+	}
+	if err := os.MkdirAll(keys.dir, 0700); err != nil {
+		return err
+	}
+	if err := keys.load(); err != nil {
+		return err
+	}
+	if _, err := keys.signing(); err != nil {
+		if err := keys.rotate(); err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		for {
+			time.Sleep(keyDirReloadInterval)
+
+			// Подхватываем ключи, добавленные другими экземплярами сервиса
+			if err := keys.load(); err != nil {
+				log.Println("Ошибка загрузки ключей JWT:", err)
+				continue
+			}
+			if current, err := keys.signing(); err != nil || time.Since(current.CreatedAt) > keyRotation() {
+				if err := keys.rotate(); err != nil {
+					log.Println("Ошибка ротации ключа JWT:", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKSHandler godoc
+// @Summary Открытые ключи JWT
+// @Description Набор открытых ключей (JWKS) для проверки токенов доступа другими сервисами
+// @Tags auth
+// @Produce json
+// @Success 200 {object} JWKSet "Открытые ключи"
+// @Router /.well-known/jwks.json [get]
+func JWKSHandler(c *gin.Context) {
+	set := JWKSet{Keys: make([]JWK, 0)}
+	for _, key := range keys.all() {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.Public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"cs-market/internal/storage"
+	"cs-market/internal/users"
 	"net/http"
 	"strings"
 
@@ -24,8 +26,74 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Токен действует, пока не отозвана сессия, в которой он выдан
+		if claims.SessionID == "" || sessionRevoked(claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Сессия завершена"})
+			c.Abort()
+			return
+		}
+
 		// Сохранение идентификатора пользователя в контексте Gin
-		c.Set("user_id", claims["user_id"].(string))
+		c.Set("user_id", claims.Subject)
+		c.Set("session_id", claims.SessionID)
+		c.Set("roles", claims.Roles)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// RequireRole пропускает запрос, только если у пользователя есть одна из ролей.
+// Должен стоять после AuthMiddleware
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, role := range roles {
+			if HasRole(c, role) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		c.Abort()
+	}
+}
+
+// RequireNotRestricted отклоняет запрос, если у пользователя есть действующая блокировка в области scope.
+// Должен стоять после AuthMiddleware
+func RequireNotRestricted(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user users.User
+		if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не найден"})
+			c.Abort()
+			return
+		}
+
+		ban, err := users.ActiveBan(storage.DB, user.ID, scope)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки ограничений"})
+			c.Abort()
+			return
+		}
+		if ban != nil {
+			abortRestricted(c, ban)
+			return
+		}
 		c.Next()
 	}
 }
+
+// abortRestricted отвечает 403 с областью, причиной и сроком блокировки
+func abortRestricted(c *gin.Context, ban *users.Ban) {
+	message := "Действие ограничено"
+	if ban.Scope == users.BanScopeLogin {
+		message = "Аккаунт заблокирован"
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":      message,
+		"scope":      ban.Scope,
+		"reason":     ban.Reason,
+		"expires_at": ban.ExpiresAt,
+	})
+	c.Abort()
+}
@@ -0,0 +1,31 @@
+package auth
+
+import "time"
+
+// RefreshToken - выданный refresh-токен. Все токены одной цепочки ротации имеют общий Family
+type RefreshToken struct {
+	ID        string    `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Family    string    `gorm:"not null;index"`
+	IssuedAt  time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	// UsedAt - время обмена токена на новую пару, повторное использование означает кражу
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	UserAgent string
+	IP        string
+}
+
+// AuthCode - одноразовый код, которым фронтенд после входа через Steam получает токены.
+// Хранится только хеш кода
+type AuthCode struct {
+	CodeHash  string    `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type ExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
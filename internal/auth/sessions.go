@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"cs-market/internal/storage"
+	"cs-market/internal/users"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Session - устройство, на котором выполнен вход. Соответствует цепочке ротации refresh-токенов
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+func sessionRevoked(sessionID string) bool {
+	var count int64
+	err := storage.DB.Model(&RefreshToken{}).
+		Where("family = ? AND revoked_at IS NOT NULL", sessionID).
+		Count(&count).Error
+	return err != nil || count > 0
+}
+
+// @Security BearerAuth
+// GetSessionsHandler godoc
+// @Summary Активные сессии
+// @Description Получение списка устройств, на которых выполнен вход
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {array} Session "Сессии"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения сессий"
+// @Router /profile/sessions [get]
+func GetSessionsHandler(c *gin.Context) {
+	var user users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	sessions := make([]Session, 0)
+	err := storage.DB.Model(&RefreshToken{}).
+		Select("family AS id, "+
+			"MIN(issued_at) AS created_at, "+
+			"MAX(issued_at) AS last_used_at, "+
+			"(array_agg(user_agent ORDER BY issued_at DESC))[1] AS user_agent, "+
+			"(array_agg(ip ORDER BY issued_at DESC))[1] AS ip").
+		Where("user_id = ?", user.ID).
+		Group("family").
+		Having("bool_and(revoked_at IS NULL) AND MAX(expires_at) > ?", time.Now()).
+		Order("last_used_at DESC").
+		Scan(&sessions).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения сессий"})
+		return
+	}
+
+	current := c.GetString("session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// @Security BearerAuth
+// RevokeSessionHandler godoc
+// @Summary Завершение сессии
+// @Description Отзывает сессию на выбранном устройстве, её токены перестают действовать
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID сессии"
+// @Success 200 {object} response.SuccessResponse "Сессия завершена"
+// @Failure 404 {object} response.ErrorResponse "Сессия не найдена"
+// @Failure 500 {object} response.ErrorResponse "Ошибка завершения сессии"
+// @Router /profile/sessions/{id} [delete]
+func RevokeSessionHandler(c *gin.Context) {
+	var user users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	result := storage.DB.Model(&RefreshToken{}).
+		Where("family = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), user.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка завершения сессии"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Сессия не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Сессия завершена"})
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"cs-market/internal/steamapi"
+	"cs-market/internal/users"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// refreshSteamTrust обновляет у пользователя данные о блокировках и профиле Steam,
+// выдаёт или снимает автоматическое ограничение торговли и возвращает нарушенные правила
+func refreshSteamTrust(ctx context.Context, db *gorm.DB, user *users.User, summary steamapi.PlayerSummary) ([]users.TrustViolation, error) {
+	bans, err := steamapi.Default().PlayerBans(ctx, user.SteamID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Закрытость инвентаря проверяется отдельно от профиля. Если Steam не ответил, остаётся прежнее значение
+	inventoryPrivate, err := steamapi.Default().InventoryPrivate(ctx, user.SteamID, steamapi.AppIDCS2, steamapi.ContextIDCS2)
+	if err != nil {
+		log.Printf("Не удалось проверить инвентарь %s: %v", user.SteamID, err)
+		inventoryPrivate = user.InventoryPrivate
+	}
+
+	now := time.Now()
+	var createdAt *time.Time
+	if summary.TimeCreated > 0 {
+		t := time.Unix(summary.TimeCreated, 0)
+		createdAt = &t
+	}
+	user.VACBanned = bans.VACBanned
+	user.NumberOfVACBans = bans.NumberOfVACBans
+	user.NumberOfGameBans = bans.NumberOfGameBans
+	user.CommunityBanned = bans.CommunityBanned
+	user.EconomyBan = bans.EconomyBan
+	user.DaysSinceLastBan = bans.DaysSinceLastBan
+	user.CommunityVisibility = summary.CommunityVisibilityState
+	user.InventoryPrivate = inventoryPrivate
+	user.SteamCreatedAt = createdAt
+	user.TrustCheckedAt = &now
+
+	err = db.Model(user).Select(
+		"vac_banned", "number_of_vac_bans", "number_of_game_bans", "community_banned", "economy_ban",
+		"days_since_last_ban", "community_visibility", "inventory_private", "steam_created_at", "trust_checked_at",
+	).Updates(user).Error
+	if err != nil {
+		return nil, err
+	}
+
+	violations := user.CheckTrust(users.TrustRules())
+	if err := users.ApplyTrustRestrictions(db, user.ID, violations); err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"crypto/rand"
+	"cs-market/internal/storage"
+	"cs-market/internal/users"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrTokenRevoked = errors.New("refresh_token отозван")
+	ErrTokenReused  = errors.New("повторное использование refresh_token")
+)
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// issueTokens выпускает пару токенов и сохраняет refresh-токен.
+// Пустой family начинает новую цепочку ротации
+func issueTokens(tx *gorm.DB, user users.User, family string, c *gin.Context) (string, string, error) {
+	if family == "" {
+		family = newID()
+	}
+	jti := newID()
+
+	accessToken, refreshToken, err := GenerateTokensJWT(user.SteamID, []string{user.Role}, jti, family)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	record := RefreshToken{
+		ID:        jti,
+		UserID:    user.ID,
+		Family:    family,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(refreshTokenTTL),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// rotateRefreshToken обменивает refresh-токен на новую пару. Токен одноразовый:
+// при повторном предъявлении отзывается вся цепочка, к которой он принадлежит
+func rotateRefreshToken(jti string, c *gin.Context) (string, string, error) {
+	var accessToken, refreshToken string
+	var reused *RefreshToken
+
+	err := storage.DB.Transaction(func(tx *gorm.DB) error {
+		var token RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", jti).First(&token).Error
+		if err != nil {
+			return err
+		}
+		if token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+			return ErrTokenRevoked
+		}
+		if token.UsedAt != nil {
+			reused = &token
+			return ErrTokenReused
+		}
+
+		now := time.Now()
+		if err := tx.Model(&token).Update("used_at", &now).Error; err != nil {
+			return err
+		}
+
+		var user users.User
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return err
+		}
+		ban, err := users.ActiveBan(tx, user.ID, users.BanScopeLogin)
+		if err != nil {
+			return err
+		}
+		if ban != nil {
+			return ErrTokenRevoked
+		}
+
+		accessToken, refreshToken, err = issueTokens(tx, user, token.Family, c)
+		return err
+	})
+
+	if reused != nil {
+		if err := revokeFamily(storage.DB, reused.Family); err != nil {
+			log.Println("Ошибка отзыва цепочки токенов:", err)
+		}
+	}
+	return accessToken, refreshToken, err
+}
+
+// revokeFamily отзывает все токены цепочки ротации
+func revokeFamily(db *gorm.DB, family string) error {
+	return db.Model(&RefreshToken{}).
+		Where("family = ? AND revoked_at IS NULL", family).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserTokens отзывает все refresh-токены пользователя
+func RevokeUserTokens(db *gorm.DB, userID uint) error {
+	return db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,156 @@
+package inventory
+
+import (
+	"cs-market/internal/storage"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRefreshCooldown = errors.New("инвентарь недавно обновлялся")
+
+const (
+	defaultCacheTTL        = 10 * time.Minute
+	defaultRefreshCooldown = time.Minute
+)
+
+// CachedInventory - инвентарь из кеша вместе с признаком устаревания
+type CachedInventory struct {
+	Inventory *Inventory
+	FetchedAt time.Time
+	// Stale - данные старше TTL или Steam не ответил при обновлении
+	Stale bool
+}
+
+// Пользователи, для которых сейчас идёт фоновое обновление
+var refreshing sync.Map
+
+// CacheTTL - время, в течение которого кеш инвентаря считается свежим (INVENTORY_CACHE_TTL)
+func CacheTTL() time.Duration {
+	return durationEnv("INVENTORY_CACHE_TTL", defaultCacheTTL)
+}
+
+// RefreshCooldown - минимальный интервал между принудительными обновлениями (INVENTORY_REFRESH_COOLDOWN)
+func RefreshCooldown() time.Duration {
+	return durationEnv("INVENTORY_REFRESH_COOLDOWN", defaultRefreshCooldown)
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// LoadInventory отдаёт инвентарь пользователя по политике stale-while-revalidate:
+// свежий кеш возвращается сразу, устаревший возвращается и обновляется в фоне,
+// при отсутствии кеша или force инвентарь загружается из Steam синхронно
+func LoadInventory(steamID string, force bool) (*CachedInventory, error) {
+	var cache InventoryCache
+	err := storage.DB.Where("steam_id = ?", steamID).First(&cache).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	exists := err == nil
+
+	if force {
+		if exists {
+			if err := claimForcedRefresh(steamID); err != nil {
+				return nil, err
+			}
+		}
+		return refreshInventory(steamID, &cache, exists, true)
+	}
+
+	if !exists {
+		return refreshInventory(steamID, &cache, false, false)
+	}
+
+	stale := time.Since(cache.FetchedAt) > CacheTTL()
+	if stale {
+		if _, running := refreshing.LoadOrStore(steamID, true); !running {
+			current := cache
+			go func() {
+				defer refreshing.Delete(steamID)
+				if _, err := refreshInventory(steamID, &current, true, false); err != nil {
+					log.Printf("Ошибка фонового обновления инвентаря %s: %v", steamID, err)
+				}
+			}()
+		}
+	}
+
+	return cachedResult(&cache, stale)
+}
+
+// claimForcedRefresh отмечает попытку принудительного обновления до запроса к Steam,
+// чтобы ошибки и ответы 429 тоже попадали под RefreshCooldown. Отметка ставится атомарно,
+// поэтому из параллельных запросов в Steam уходит только один
+func claimForcedRefresh(steamID string) error {
+	now := time.Now()
+	result := storage.DB.Model(&InventoryCache{}).
+		Where("steam_id = ? AND (forced_at IS NULL OR forced_at < ?)", steamID, now.Add(-RefreshCooldown())).
+		Update("forced_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRefreshCooldown
+	}
+	return nil
+}
+
+// FetchInventory загружает свежий инвентарь из Steam в обход TTL и обновляет кеш.
+// В отличие от LoadInventory не возвращает устаревшую копию, если Steam недоступен
+func FetchInventory(steamID string) (*Inventory, error) {
+	result, err := refreshInventory(steamID, &InventoryCache{}, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return result.Inventory, nil
+}
+
+// refreshInventory загружает инвентарь из Steam и сохраняет его в кеш.
+// Если Steam недоступен, а кеш есть, возвращается сохранённая копия с флагом stale
+func refreshInventory(steamID string, cache *InventoryCache, exists, forced bool) (*CachedInventory, error) {
+	data, err := fetchInventoryData(steamID)
+	if err != nil {
+		if exists {
+			log.Printf("Steam недоступен, используется кеш инвентаря %s: %v", steamID, err)
+			return cachedResult(cache, true)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	fresh := InventoryCache{SteamID: steamID, Data: data, FetchedAt: now}
+	columns := []string{"data", "fetched_at", "updated_at"}
+	if forced {
+		fresh.ForcedAt = &now
+		columns = append(columns, "forced_at")
+	}
+
+	err = storage.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "steam_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&fresh).Error
+	if err != nil {
+		log.Printf("Ошибка сохранения кеша инвентаря %s: %v", steamID, err)
+	}
+
+	return cachedResult(&fresh, false)
+}
+
+func cachedResult(cache *InventoryCache, stale bool) (*CachedInventory, error) {
+	inv, err := ParseInventory(cache.Data)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка парсинга инвентаря: %w", err)
+	}
+	inv.OwnerSteamID = cache.SteamID
+	return &CachedInventory{Inventory: inv, FetchedAt: cache.FetchedAt, Stale: stale}, nil
+}
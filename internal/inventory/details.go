@@ -0,0 +1,94 @@
+package inventory
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ItemDetails - метаданные предмета, извлечённые из описания Steam.
+// Float в описании не передаётся, его можно получить только через ссылку осмотра
+type ItemDetails struct {
+	Type           string     `json:"type" gorm:"index"`
+	Weapon         string     `json:"weapon" gorm:"index"`
+	Quality        string     `json:"quality"`
+	Rarity         string     `json:"rarity" gorm:"index"`
+	RarityColor    string     `json:"rarity_color"`
+	Exterior       string     `json:"exterior" gorm:"index"`
+	Collection     string     `json:"collection"`
+	NameColor      string     `json:"name_color"`
+	StatTrak       bool       `json:"stattrak"`
+	Souvenir       bool       `json:"souvenir"`
+	Stickers       []string   `json:"stickers" gorm:"serializer:json"`
+	Charms         []string   `json:"charms" gorm:"serializer:json"`
+	InspectLink    string     `json:"inspect_link"`
+	TradeHoldUntil *time.Time `json:"trade_hold_until"`
+}
+
+var (
+	stickerInfoRe = regexp.MustCompile(`(?s)<br>Sticker: (.*?)</(?:center|div)>`)
+	charmInfoRe   = regexp.MustCompile(`(?s)<br>Charm: (.*?)</(?:center|div)>`)
+)
+
+// Details собирает метаданные предмета. assetID и ownerSteamID подставляются в ссылку осмотра
+func (d *Description) Details(assetID, ownerSteamID string) ItemDetails {
+	details := ItemDetails{
+		Type:       d.Tag(TagType),
+		Weapon:     d.Tag(TagWeapon),
+		Quality:    d.Tag(TagQuality),
+		Rarity:     d.Tag(TagRarity),
+		Exterior:   d.Tag(TagExterior),
+		Collection: d.Tag(TagCollection),
+		NameColor:  d.NameColor,
+	}
+
+	if tag := d.findTag(TagRarity); tag != nil {
+		details.RarityColor = tag.Color
+	}
+	if tag := d.findTag(TagQuality); tag != nil {
+		details.StatTrak = tag.InternalName == "strange"
+		details.Souvenir = tag.InternalName == "tournament"
+	}
+
+	for _, line := range d.Descriptions {
+		switch line.Name {
+		case "sticker_info":
+			details.Stickers = parseAttachments(stickerInfoRe, line.Value)
+		case "keychain_info":
+			details.Charms = parseAttachments(charmInfoRe, line.Value)
+		}
+	}
+
+	for _, action := range d.Actions {
+		if strings.Contains(action.Link, "csgo_econ_action_preview") {
+			link := strings.ReplaceAll(action.Link, "%assetid%", assetID)
+			details.InspectLink = strings.ReplaceAll(link, "%owner_steamid%", ownerSteamID)
+			break
+		}
+	}
+
+	if d.CacheExpiration != "" {
+		if t, err := time.Parse(time.RFC3339, d.CacheExpiration); err == nil {
+			details.TradeHoldUntil = &t
+		}
+	}
+
+	return details
+}
+
+// parseAttachments достаёт названия наклеек или брелоков из HTML-блока описания
+func parseAttachments(re *regexp.Regexp, value string) []string {
+	match := re.FindStringSubmatch(value)
+	if match == nil {
+		return nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(html.UnescapeString(match[1]), ", ") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,122 @@
+package inventory
+
+import (
+	"cs-market/internal/storage"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultHistoryPeriod    = 30 * 24 * time.Hour
+	defaultSnapshotInterval = time.Hour
+	defaultHistoryRetention = 365 * 24 * time.Hour
+	pruneBatchSize          = 10000
+)
+
+// SnapshotInterval - как часто неизменная цена всё равно записывается в историю (PRICE_SNAPSHOT_INTERVAL)
+func SnapshotInterval() time.Duration {
+	return durationEnv("PRICE_SNAPSHOT_INTERVAL", defaultSnapshotInterval)
+}
+
+// HistoryRetention - сколько хранится история цен (PRICE_HISTORY_RETENTION)
+func HistoryRetention() time.Duration {
+	return durationEnv("PRICE_HISTORY_RETENTION", defaultHistoryRetention)
+}
+
+// PrunePriceHistory удаляет записи истории цен старше HistoryRetention.
+// Удаление идёт пачками, чтобы не держать долгую блокировку таблицы
+func PrunePriceHistory(db *gorm.DB) {
+	before := time.Now().Add(-HistoryRetention())
+	var total int64
+	for {
+		result := db.Exec("DELETE FROM skin_price_snapshots WHERE id IN (SELECT id FROM skin_price_snapshots WHERE created_at < ? LIMIT ?)",
+			before, pruneBatchSize)
+		if result.Error != nil {
+			fmt.Println("Ошибка удаления старой истории цен:", result.Error)
+			return
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < pruneBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		fmt.Printf("Удалено записей истории цен: %d\n", total)
+	}
+}
+
+var historyIntervals = map[string]bool{"hour": true, "day": true, "week": true}
+
+// GetPriceHistoryHandler godoc
+// @Summary История цен скина
+// @Description Получение OHLC-свечей минимальной цены скина за период
+// @Tags skins
+// @Accept json
+// @Produce json
+// @Param market_hash_name path string true "market_hash_name скина"
+// @Param from query string false "Начало периода (RFC3339), по умолчанию 30 дней назад"
+// @Param to query string false "Конец периода (RFC3339), по умолчанию текущее время"
+// @Param interval query string false "Интервал свечи" Enums(hour, day, week)
+// @Success 200 {object} PriceHistoryResponse "История цен"
+// @Failure 400 {object} response.ErrorResponse "Некорректные параметры"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения истории цен"
+// @Router /skins/{market_hash_name}/history [get]
+func GetPriceHistoryHandler(c *gin.Context) {
+	name := c.Param("market_hash_name")
+
+	interval := c.DefaultQuery("interval", "day")
+	if !historyIntervals[interval] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный интервал"})
+		return
+	}
+
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр to"})
+			return
+		}
+		to = t
+	}
+	from := to.Add(-defaultHistoryPeriod)
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр from"})
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Начало периода должно быть раньше конца"})
+		return
+	}
+
+	points := make([]PricePoint, 0)
+	err := storage.DB.Model(&SkinPriceSnapshot{}).
+		Select("date_trunc(?, created_at) AS time, "+
+			"(array_agg(min_price ORDER BY created_at))[1] AS open, "+
+			"MAX(min_price) AS high, "+
+			"MIN(min_price) AS low, "+
+			"(array_agg(min_price ORDER BY created_at DESC))[1] AS close, "+
+			"AVG(avg_price) AS avg", interval).
+		Where("market_hash_name = ? AND created_at >= ? AND created_at < ? AND min_price IS NOT NULL", name, from, to).
+		Group("1").
+		Order("1").
+		Scan(&points).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения истории цен"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PriceHistoryResponse{
+		MarketHashName: name,
+		Interval:       interval,
+		Points:         points,
+	})
+}
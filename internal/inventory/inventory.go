@@ -1,15 +1,17 @@
 package inventory
 
 import (
+	"context"
+	"cs-market/internal/steamapi"
 	"cs-market/internal/storage"
 	"cs-market/internal/users"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/andybalholm/brotli"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -18,12 +20,16 @@ import (
 // @Security BearerAuth
 // GetMyInventoryHandler godoc
 // @Summary Получение инвентаря пользователя
-// @Description Получение инвентаря пользователя
+// @Description Получение инвентаря пользователя. Инвентарь кешируется на сервере, при недоступности Steam отдаётся сохранённая копия с флагом stale
 // @Tags users
 // @Accept json
 // @Produce json
+// @Param refresh query bool false "Принудительно обновить инвентарь из Steam"
+// @Param group query bool false "Сгруппировать предметы по market_name"
 // @Success 200 {object} response.SuccessResponse "Информация об инвентаре"
+// @Failure 403 {object} response.ErrorResponse "Инвентарь Steam закрыт"
 // @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 429 {object} response.ErrorResponse "Слишком частое обновление инвентаря"
 // @Router /profile/inventory [get]
 func GetMyInventoryHandler(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -34,54 +40,287 @@ func GetMyInventoryHandler(c *gin.Context) {
 		return
 	}
 
-	// Получение инвентаря пользователя
-	url := fmt.Sprintf("https://steamcommunity.com/inventory/%s/730/2?l=english&count=5000", user.SteamID)
-
-	resp, err := http.Get(url)
+	cached, err := LoadInventory(user.SteamID, c.Query("refresh") == "true")
 	if err != nil {
+		if errors.Is(err, ErrRefreshCooldown) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Слишком частое обновление инвентаря"})
+			return
+		}
+		if errors.Is(err, steamapi.ErrPrivateInventory) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Инвентарь Steam закрыт"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения инвентаря"})
 		return
 	}
-	defer resp.Body.Close()
+	date := cached.Inventory
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка чтения ответа"})
-		return
+	// Собираем только продаваемые предметы
+	marketableItems := make([]InventoryItem, 0, len(date.Assets))
+	for _, item := range date.Items() {
+		if item.Marketable && item.Tradable {
+			marketableItems = append(marketableItems, item)
+		}
 	}
 
-	date, err := ParseInventory(body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка парсинга инвентаря"})
-		return
+	result := gin.H{
+		"inventory":             marketableItems,
+		"count":                 len(marketableItems),
+		"total_inventory_count": date.TotalInventoryCount,
+		"stale":                 cached.Stale,
+		"fetched_at":            cached.FetchedAt,
+	}
+	if c.Query("group") == "true" {
+		result["inventory"] = GroupItems(marketableItems)
 	}
 
-	marketableItems := make([]interface{}, 0) // Используем interface{} для универсальности
+	c.JSON(http.StatusOK, result)
+}
+
+const (
+	cs2AppID              = 730
+	cs2ContextID          = 2
+	inventoryPageSize     = 2000
+	maxInventoryPages     = 50
+	defaultInventoryDelay = 1500 * time.Millisecond
+)
+
+// fetchInventoryData загружает инвентарь CS2 пользователя из Steam постранично
+// по курсору last_assetid и склеивает страницы в один ответ
+func fetchInventoryData(steamID string) ([]byte, error) {
+	delay := durationEnv("INVENTORY_PAGE_DELAY", defaultInventoryDelay)
+
+	var merged steamapi.InventoryPage
+	seen := make(map[string]bool)
+	startAssetID := ""
+
+	for page := 0; page < maxInventoryPages; page++ {
+		if page > 0 {
+			// Пауза между страницами, чтобы не получить 429 от Steam
+			time.Sleep(delay)
+		}
+
+		data, err := steamapi.Default().Inventory(context.Background(), steamID, cs2AppID, cs2ContextID, inventoryPageSize, startAssetID)
+		if err != nil {
+			return nil, err
+		}
+
+		merged.Assets = append(merged.Assets, data.Assets...)
+		merged.TotalInventoryCount = data.TotalInventoryCount
+		for _, raw := range data.Descriptions {
+			var key struct {
+				ClassID    string `json:"classid"`
+				InstanceID string `json:"instanceid"`
+			}
+			if err := json.Unmarshal(raw, &key); err != nil {
+				return nil, fmt.Errorf("ошибка разбора описания: %w", err)
+			}
+			if k := descriptionKey(key.ClassID, key.InstanceID); !seen[k] {
+				seen[k] = true
+				merged.Descriptions = append(merged.Descriptions, raw)
+			}
+		}
 
-	for _, desc := range date.Descriptions {
-		if desc.Marketable == 1 && desc.Tradable == 1 {
-			// Собираем только продаваемые предметы
-			marketableItems = append(marketableItems, desc)
+		if data.MoreItems != 1 || data.LastAssetID == "" {
+			break
 		}
+		startAssetID = data.LastAssetID
 	}
 
-	// Отправляем фильтрованный список
-	c.JSON(http.StatusOK, gin.H{"inventory": marketableItems})
+	return json.Marshal(merged)
 }
 
 type Inventory struct {
-	Assets []struct {
-		AssetID string `json:"assetid"`
-		ClassID string `json:"classid"`
-	} `json:"assets"`
-	Descriptions []struct {
-		ClassID    string   `json:"classid"`
-		MarketName string   `json:"market_name"`
-		IconURL    string   `json:"icon_url"`
-		Price      *float64 `json:"price"`
-		Marketable int      `json:"marketable"`
-		Tradable   int      `json:"tradable"`
-	} `json:"descriptions"`
+	Assets              []Asset       `json:"assets"`
+	Descriptions        []Description `json:"descriptions"`
+	TotalInventoryCount int           `json:"total_inventory_count"`
+	// OwnerSteamID нужен для подстановки в ссылки осмотра предметов
+	OwnerSteamID string `json:"-"`
+}
+
+type Asset struct {
+	AssetID    string `json:"assetid"`
+	ClassID    string `json:"classid"`
+	InstanceID string `json:"instanceid"`
+	Amount     string `json:"amount"`
+}
+
+type Description struct {
+	ClassID         string            `json:"classid"`
+	InstanceID      string            `json:"instanceid"`
+	MarketName      string            `json:"market_name"`
+	MarketHashName  string            `json:"market_hash_name"`
+	IconURL         string            `json:"icon_url"`
+	NameColor       string            `json:"name_color"`
+	Price           *float64          `json:"price"`
+	Marketable      int               `json:"marketable"`
+	Tradable        int               `json:"tradable"`
+	CacheExpiration string            `json:"cache_expiration"`
+	Tags            []Tag             `json:"tags"`
+	Descriptions    []DescriptionLine `json:"descriptions"`
+	Actions         []Action          `json:"actions"`
+}
+
+type Tag struct {
+	Category         string `json:"category"`
+	InternalName     string `json:"internal_name"`
+	LocalizedTagName string `json:"localized_tag_name"`
+	Color            string `json:"color"`
+}
+
+// DescriptionLine - строка описания предмета, в том числе HTML с наклейками и брелоками
+type DescriptionLine struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Name  string `json:"name"`
+}
+
+// Action - действие над предметом, например ссылка для осмотра в игре
+type Action struct {
+	Link string `json:"link"`
+	Name string `json:"name"`
+}
+
+// Категории тегов Steam
+const (
+	TagType       = "Type"
+	TagWeapon     = "Weapon"
+	TagQuality    = "Quality"
+	TagRarity     = "Rarity"
+	TagExterior   = "Exterior"
+	TagCollection = "ItemSet"
+)
+
+// Tag возвращает локализованное значение тега указанной категории
+func (d *Description) Tag(category string) string {
+	if tag := d.findTag(category); tag != nil {
+		return tag.LocalizedTagName
+	}
+	return ""
+}
+
+func (d *Description) findTag(category string) *Tag {
+	for i := range d.Tags {
+		if d.Tags[i].Category == category {
+			return &d.Tags[i]
+		}
+	}
+	return nil
+}
+
+// InventoryItem - отдельный экземпляр предмета в инвентаре
+type InventoryItem struct {
+	AssetID        string      `json:"assetid"`
+	ClassID        string      `json:"classid"`
+	InstanceID     string      `json:"instanceid"`
+	Amount         int         `json:"amount"`
+	MarketName     string      `json:"market_name"`
+	MarketHashName string      `json:"market_hash_name"`
+	IconURL        string      `json:"icon_url"`
+	Price          *float64    `json:"price"`
+	Marketable     bool        `json:"marketable"`
+	Tradable       bool        `json:"tradable"`
+	Details        ItemDetails `json:"details"`
+}
+
+// InventoryGroup - одинаковые предметы, сгруппированные по market_name
+type InventoryGroup struct {
+	MarketName string   `json:"market_name"`
+	IconURL    string   `json:"icon_url"`
+	Price      *float64 `json:"price"`
+	Count      int      `json:"count"`
+	AssetIDs   []string `json:"assetids"`
+}
+
+func descriptionKey(classID, instanceID string) string {
+	return classID + "_" + instanceID
+}
+
+// Items сопоставляет предметы инвентаря с их описаниями по classid и instanceid
+func (inv *Inventory) Items() []InventoryItem {
+	descriptions := make(map[string]*Description, len(inv.Descriptions))
+	for i := range inv.Descriptions {
+		desc := &inv.Descriptions[i]
+		descriptions[descriptionKey(desc.ClassID, desc.InstanceID)] = desc
+	}
+
+	items := make([]InventoryItem, 0, len(inv.Assets))
+	for _, asset := range inv.Assets {
+		desc, ok := descriptions[descriptionKey(asset.ClassID, asset.InstanceID)]
+		if !ok {
+			continue
+		}
+		amount, err := strconv.Atoi(asset.Amount)
+		if err != nil {
+			amount = 1
+		}
+		items = append(items, InventoryItem{
+			AssetID:        asset.AssetID,
+			ClassID:        asset.ClassID,
+			InstanceID:     asset.InstanceID,
+			Amount:         amount,
+			MarketName:     desc.MarketName,
+			MarketHashName: desc.MarketHashName,
+			IconURL:        desc.IconURL,
+			Price:          desc.Price,
+			Marketable:     desc.Marketable == 1,
+			Tradable:       desc.Tradable == 1,
+			Details:        desc.Details(asset.AssetID, inv.OwnerSteamID),
+		})
+	}
+	return items
+}
+
+// GroupItems группирует предметы по market_name с сохранением порядка
+func GroupItems(items []InventoryItem) []InventoryGroup {
+	groups := make([]InventoryGroup, 0)
+	index := make(map[string]int)
+	for _, item := range items {
+		i, ok := index[item.MarketName]
+		if !ok {
+			i = len(groups)
+			index[item.MarketName] = i
+			groups = append(groups, InventoryGroup{
+				MarketName: item.MarketName,
+				IconURL:    item.IconURL,
+				Price:      item.Price,
+			})
+		}
+		groups[i].Count += item.Amount
+		groups[i].AssetIDs = append(groups[i].AssetIDs, item.AssetID)
+	}
+	return groups
+}
+
+// FindItem ищет предмет инвентаря по assetid
+func (inv *Inventory) FindItem(assetID string) (InventoryItem, bool) {
+	for _, item := range inv.Items() {
+		if item.AssetID == assetID {
+			return item, true
+		}
+	}
+	return InventoryItem{}, false
+}
+
+// HasAsset проверяет, есть ли в инвентаре предмет с заданным assetid
+func (inv *Inventory) HasAsset(assetID string) bool {
+	for _, asset := range inv.Assets {
+		if asset.AssetID == assetID {
+			return true
+		}
+	}
+	return false
+}
+
+// AssetIDsOf возвращает assetid всех предметов с заданными classid и instanceid
+func (inv *Inventory) AssetIDsOf(classID, instanceID string) []string {
+	ids := make([]string, 0)
+	for _, asset := range inv.Assets {
+		if asset.ClassID == classID && asset.InstanceID == instanceID {
+			ids = append(ids, asset.AssetID)
+		}
+	}
+	return ids
 }
 
 func ParseInventory(data []byte) (*Inventory, error) {
@@ -99,7 +338,7 @@ func ParseInventory(data []byte) (*Inventory, error) {
 	// Собираем все market_hash_name для запроса в базу данных
 	var marketNames []string
 	for _, desc := range inv.Descriptions {
-		marketNames = append(marketNames, desc.MarketName)
+		marketNames = append(marketNames, desc.MarketHashName)
 	}
 
 	// Запрос в базу данных для получения всех скинов
@@ -108,7 +347,7 @@ func ParseInventory(data []byte) (*Inventory, error) {
 		return nil, fmt.Errorf("ошибка при получении скинов из базы данных: %w", err)
 	}
 
-	// Преобразуем скины в мапу для быстрого поиска по MarketName
+	// Преобразуем скины в мапу для быстрого поиска по MarketHashName
 	skinMap := make(map[string]Skin)
 	for _, skin := range skins {
 		skinMap[skin.MarketHashName] = skin
@@ -116,7 +355,7 @@ func ParseInventory(data []byte) (*Inventory, error) {
 
 	// Присваиваем цену каждому элементу, если скин найден в базе данных
 	for i := range inv.Descriptions {
-		if skin, exists := skinMap[inv.Descriptions[i].MarketName]; exists {
+		if skin, exists := skinMap[inv.Descriptions[i].MarketHashName]; exists {
 			inv.Descriptions[i].Price = skin.MinPrice
 		} else {
 			// Если скин не найден в базе данных, устанавливаем цену как nil
@@ -127,55 +366,125 @@ func ParseInventory(data []byte) (*Inventory, error) {
 	return &inv, nil
 }
 
-// https://api.skinport.com/v1/items?app_id=730&currency=RUB&tradable=0
-
 func UpdatePrices(db *gorm.DB) {
-	url := "https://api.skinport.com/v1/items?app_id=730&currency=RUB&tradable=0"
+	providers := PriceProviders()
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("Ошибка при создании запроса:", err)
-		return
-	}
-	req.Header.Set("Accept-Encoding", "br")
+	bySource := make(map[string]map[string]SourcePrice, len(providers))
+	for _, provider := range providers {
+		// Цены в разных валютах нельзя сравнивать и усреднять, поэтому всё пересчитывается в PriceCurrency
+		rate, ok := CurrencyRate(provider.Currency())
+		if !ok {
+			fmt.Printf("Нет курса %s для источника %s, цены не учитываются\n", provider.Currency(), provider.Name())
+			continue
+		}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Ошибка при выполнении запроса:", err)
-		return
+		prices, err := provider.FetchPrices()
+		if err != nil {
+			fmt.Printf("Ошибка получения цен из %s: %v\n", provider.Name(), err)
+			continue
+		}
+
+		byName := make(map[string]SourcePrice, len(prices))
+		failed := 0
+		var lastErr error
+		for _, price := range prices {
+			price = price.Convert(rate)
+			byName[price.MarketHashName] = price
+			err := db.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "market_hash_name"}, {Name: "source"}},
+				DoUpdates: clause.AssignmentColumns([]string{"min_price", "avg_price", "max_price", "updated_at"}),
+			}).Create(&SkinSourcePrice{
+				MarketHashName: price.MarketHashName,
+				Source:         provider.Name(),
+				MinPrice:       price.MinPrice,
+				AvgPrice:       price.AvgPrice,
+				MaxPrice:       price.MaxPrice,
+			}).Error
+			if err != nil {
+				failed++
+				lastErr = err
+			}
+		}
+		if failed > 0 {
+			fmt.Printf("Не сохранено цен из %s: %d из %d, последняя ошибка: %v\n", provider.Name(), failed, len(prices), lastErr)
+		}
+		bySource[provider.Name()] = byName
 	}
-	defer resp.Body.Close()
 
-	// Декодируем Brotli-сжатый ответ
-	brReader := brotli.NewReader(resp.Body)
-	body, err := io.ReadAll(brReader)
-	if err != nil {
-		fmt.Println("Ошибка при декодировании Brotli:", err)
+	skins := AggregatePrices(providers, bySource, AggregationPolicy())
+	if len(skins) == 0 {
+		fmt.Println("Цены не обновлены: ни один источник не ответил")
 		return
 	}
 
-	var skins []Skin
-	if err := json.Unmarshal(body, &skins); err != nil {
-		fmt.Println("Ошибка при разборе JSON:", err)
-		fmt.Println("Ответ сервера:", string(body)) // Логируем, если вдруг структура неверная
+	var stored []Skin
+	if err := db.Find(&stored).Error; err != nil {
+		fmt.Println("Ошибка получения текущих цен:", err)
 		return
 	}
+	previous := make(map[string]Skin, len(stored))
+	for _, skin := range stored {
+		previous[skin.MarketHashName] = skin
+	}
 
+	now := time.Now()
+	snapshots := make([]SkinPriceSnapshot, 0, len(skins))
 	for _, skin := range skins {
-		db.Clauses(clause.OnConflict{
+		// В историю попадают только изменившиеся цены и, чтобы у графиков не было пропусков, раз в SnapshotInterval - неизменные
+		prev, ok := previous[skin.MarketHashName]
+		snapshot := !ok || prev.SnapshotAt == nil || now.Sub(*prev.SnapshotAt) >= SnapshotInterval() ||
+			!samePrice(prev.MinPrice, skin.MinPrice) || !samePrice(prev.AvgPrice, skin.AvgPrice) || !samePrice(prev.MaxPrice, skin.MaxPrice)
+
+		columns := []string{"min_price", "avg_price", "max_price", "updated_at"}
+		if snapshot {
+			skin.SnapshotAt = &now
+			columns = append(columns, "snapshot_at")
+		} else {
+			skin.SnapshotAt = prev.SnapshotAt
+		}
+		err := db.Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "market_hash_name"}},
-			DoUpdates: clause.AssignmentColumns([]string{"min_price", "avg_price", "max_price", "updated_at"}),
-		}).Create(&skin)
+			DoUpdates: clause.AssignmentColumns(columns),
+		}).Create(&skin).Error
+		if err != nil {
+			fmt.Printf("Ошибка сохранения цены %s: %v\n", skin.MarketHashName, err)
+			continue
+		}
+		if !snapshot {
+			continue
+		}
+
+		snapshots = append(snapshots, SkinPriceSnapshot{
+			MarketHashName: skin.MarketHashName,
+			MinPrice:       skin.MinPrice,
+			AvgPrice:       skin.AvgPrice,
+			MaxPrice:       skin.MaxPrice,
+			CreatedAt:      now,
+		})
+	}
+
+	// Сохраняем историю цен для графиков
+	if len(snapshots) > 0 {
+		if err := db.CreateInBatches(&snapshots, 1000).Error; err != nil {
+			fmt.Println("Ошибка сохранения истории цен:", err)
+		}
 	}
 
 	fmt.Println("Цены обновлены:", time.Now())
 }
 
+func samePrice(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
 func StartPriceUpdater(db *gorm.DB) {
 	go func() {
 		for {
 			UpdatePrices(db)
+			PrunePriceHistory(db)
 			time.Sleep(10 * time.Minute)
 		}
 	}()
@@ -8,4 +8,54 @@ type Skin struct {
 	AvgPrice       *float64  `json:"mean_price"`
 	MaxPrice       *float64  `json:"max_price"`
 	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+	// SnapshotAt - время последней записи цен в историю
+	SnapshotAt *time.Time `json:"-"`
+}
+
+// SkinPriceSnapshot - цены скина на момент обновления, в котором они изменились, но не реже раза в PRICE_SNAPSHOT_INTERVAL
+type SkinPriceSnapshot struct {
+	ID             uint   `gorm:"primaryKey"`
+	MarketHashName string `gorm:"not null;index:idx_snapshot_name_time,priority:1"`
+	MinPrice       *float64
+	AvgPrice       *float64
+	MaxPrice       *float64
+	CreatedAt      time.Time `gorm:"index:idx_snapshot_name_time,priority:2"`
+}
+
+// PricePoint - OHLC-свеча по минимальной цене за интервал
+type PricePoint struct {
+	Time  time.Time `json:"time"`
+	Open  float64   `json:"open"`
+	High  float64   `json:"high"`
+	Low   float64   `json:"low"`
+	Close float64   `json:"close"`
+	Avg   *float64  `json:"avg"`
+}
+
+type PriceHistoryResponse struct {
+	MarketHashName string       `json:"market_hash_name"`
+	Interval       string       `json:"interval"`
+	Points         []PricePoint `json:"points"`
+}
+
+// SkinSourcePrice - цены скина по отдельному источнику, из них агрегируется Skin
+type SkinSourcePrice struct {
+	MarketHashName string    `json:"market_hash_name" gorm:"primaryKey"`
+	Source         string    `json:"source" gorm:"primaryKey"`
+	MinPrice       *float64  `json:"min_price"`
+	AvgPrice       *float64  `json:"mean_price"`
+	MaxPrice       *float64  `json:"max_price"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+	// SnapshotAt - время последней записи цен в историю
+	SnapshotAt *time.Time `json:"-"`
+}
+
+// InventoryCache - сохранённый ответ Steam с инвентарём пользователя
+type InventoryCache struct {
+	SteamID   string `gorm:"primaryKey"`
+	Data      []byte `gorm:"type:jsonb;not null"`
+	FetchedAt time.Time
+	// ForcedAt - время последнего принудительного обновления, для ограничения частоты
+	ForcedAt  *time.Time
+	UpdatedAt time.Time
 }
@@ -0,0 +1,306 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Политики агрегации цен из нескольких источников
+const (
+	PolicyMin      = "min"
+	PolicyWeighted = "weighted"
+	PolicyFallback = "fallback"
+)
+
+const (
+	SourceSkinport = "skinport"
+	SourceSteam    = "steam"
+)
+
+// PriceCurrency - валюта, в которой хранятся все цены. Цены источников в другой валюте
+// пересчитываются по курсам из PRICE_RATES
+const PriceCurrency = "RUB"
+
+// SourcePrice - цены скина, полученные от одного источника
+type SourcePrice struct {
+	MarketHashName string
+	MinPrice       *float64
+	AvgPrice       *float64
+	MaxPrice       *float64
+}
+
+// Convert пересчитывает цены по курсу
+func (p SourcePrice) Convert(rate float64) SourcePrice {
+	convert := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		value := math.Round(*v*rate*100) / 100
+		return &value
+	}
+	p.MinPrice, p.AvgPrice, p.MaxPrice = convert(p.MinPrice), convert(p.AvgPrice), convert(p.MaxPrice)
+	return p
+}
+
+// PriceProvider - источник рыночных цен на скины
+type PriceProvider interface {
+	Name() string
+	FetchPrices() ([]SourcePrice, error)
+	// Weight - вес источника для политики weighted
+	Weight() float64
+	// Currency - валюта цен источника
+	Currency() string
+}
+
+var priceClient = &http.Client{Timeout: time.Minute}
+
+// SkinportProvider получает цены из публичного API Skinport
+type SkinportProvider struct {
+	URL    string
+	weight float64
+}
+
+func (p *SkinportProvider) Name() string     { return SourceSkinport }
+func (p *SkinportProvider) Weight() float64  { return p.weight }
+func (p *SkinportProvider) Currency() string { return PriceCurrency }
+
+func (p *SkinportProvider) FetchPrices() ([]SourcePrice, error) {
+	req, err := http.NewRequest("GET", p.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при создании запроса: %w", err)
+	}
+	req.Header.Set("Accept-Encoding", "br")
+
+	resp, err := priceClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при выполнении запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("skinport вернул статус %d", resp.StatusCode)
+	}
+
+	// Декодируем Brotli-сжатый ответ
+	body, err := io.ReadAll(brotli.NewReader(resp.Body))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при декодировании Brotli: %w", err)
+	}
+
+	var items []Skin
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("ошибка при разборе JSON: %w", err)
+	}
+
+	prices := make([]SourcePrice, 0, len(items))
+	for _, item := range items {
+		prices = append(prices, SourcePrice{
+			MarketHashName: item.MarketHashName,
+			MinPrice:       item.MinPrice,
+			AvgPrice:       item.AvgPrice,
+			MaxPrice:       item.MaxPrice,
+		})
+	}
+	return prices, nil
+}
+
+// SteamMarketProvider получает цены Steam Community Market из готового дампа,
+// так как priceoverview отдаёт цену по одному предмету и быстро упирается в лимиты.
+// Дамп - JSON-объект вида {"<market_hash_name>": {"lowest_price": 1.0, "median_price": 1.2}},
+// валюта дампа в нём не указана и задаётся STEAM_PRICES_CURRENCY (по умолчанию USD)
+type SteamMarketProvider struct {
+	URL      string
+	weight   float64
+	currency string
+}
+
+func (p *SteamMarketProvider) Name() string     { return SourceSteam }
+func (p *SteamMarketProvider) Weight() float64  { return p.weight }
+func (p *SteamMarketProvider) Currency() string { return p.currency }
+
+func (p *SteamMarketProvider) FetchPrices() ([]SourcePrice, error) {
+	resp, err := priceClient.Get(p.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при выполнении запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("дамп цен Steam вернул статус %d", resp.StatusCode)
+	}
+
+	var dump map[string]struct {
+		LowestPrice *float64 `json:"lowest_price"`
+		MedianPrice *float64 `json:"median_price"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&dump); err != nil {
+		return nil, fmt.Errorf("ошибка при разборе JSON: %w", err)
+	}
+
+	prices := make([]SourcePrice, 0, len(dump))
+	for name, item := range dump {
+		prices = append(prices, SourcePrice{
+			MarketHashName: name,
+			MinPrice:       item.LowestPrice,
+			AvgPrice:       item.MedianPrice,
+		})
+	}
+	return prices, nil
+}
+
+// PriceProviders собирает источники цен из окружения.
+// PRICE_SOURCES задаёт список и порядок источников (первый - основной для политики fallback),
+// PRICE_WEIGHTS - веса в формате "skinport=0.7,steam=0.3", STEAM_PRICES_URL - адрес дампа цен Steam
+func PriceProviders() []PriceProvider {
+	sources := os.Getenv("PRICE_SOURCES")
+	if sources == "" {
+		sources = SourceSkinport
+	}
+	weights := parseRates(os.Getenv("PRICE_WEIGHTS"))
+
+	var providers []PriceProvider
+	for _, name := range strings.Split(sources, ",") {
+		name = strings.TrimSpace(name)
+		weight, ok := weights[name]
+		if !ok {
+			weight = 1
+		}
+
+		switch name {
+		case SourceSkinport:
+			providers = append(providers, &SkinportProvider{
+				URL:    "https://api.skinport.com/v1/items?app_id=730&currency=" + PriceCurrency + "&tradable=0",
+				weight: weight,
+			})
+		case SourceSteam:
+			url := os.Getenv("STEAM_PRICES_URL")
+			if url == "" {
+				fmt.Println("STEAM_PRICES_URL не задан, источник steam отключён")
+				continue
+			}
+			currency := strings.ToUpper(strings.TrimSpace(os.Getenv("STEAM_PRICES_CURRENCY")))
+			if currency == "" {
+				currency = "USD"
+			}
+			providers = append(providers, &SteamMarketProvider{URL: url, weight: weight, currency: currency})
+		default:
+			fmt.Println("Неизвестный источник цен:", name)
+		}
+	}
+	return providers
+}
+
+// parseRates разбирает пары вида "name=1.5,other=2"
+func parseRates(raw string) map[string]float64 {
+	rates := make(map[string]float64)
+	for _, pair := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		if w, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && w >= 0 {
+			rates[strings.TrimSpace(name)] = w
+		}
+	}
+	return rates
+}
+
+// CurrencyRate возвращает курс пересчёта валюты в PriceCurrency.
+// Курсы задаются PRICE_RATES в формате "USD=92.5,EUR=100"
+func CurrencyRate(currency string) (float64, bool) {
+	if currency == PriceCurrency {
+		return 1, true
+	}
+	rate, ok := parseRates(strings.ToUpper(os.Getenv("PRICE_RATES")))[strings.ToUpper(currency)]
+	return rate, ok && rate > 0
+}
+
+// AggregationPolicy возвращает политику агрегации из PRICE_AGGREGATION (по умолчанию fallback)
+func AggregationPolicy() string {
+	switch policy := os.Getenv("PRICE_AGGREGATION"); policy {
+	case PolicyMin, PolicyWeighted, PolicyFallback:
+		return policy
+	default:
+		return PolicyFallback
+	}
+}
+
+// AggregatePrices сводит цены источников в итоговые цены скинов по выбранной политике.
+// bySource - цены каждого источника по market_hash_name
+func AggregatePrices(providers []PriceProvider, bySource map[string]map[string]SourcePrice, policy string) []Skin {
+	names := make(map[string]struct{})
+	for _, prices := range bySource {
+		for name := range prices {
+			names[name] = struct{}{}
+		}
+	}
+
+	skins := make([]Skin, 0, len(names))
+	for name := range names {
+		var available []SourcePrice
+		var weights []float64
+		for _, provider := range providers {
+			if price, ok := bySource[provider.Name()][name]; ok {
+				available = append(available, price)
+				weights = append(weights, provider.Weight())
+			}
+		}
+
+		skin := Skin{MarketHashName: name}
+		switch policy {
+		case PolicyMin:
+			skin.MinPrice = minOf(available, func(p SourcePrice) *float64 { return p.MinPrice })
+			skin.AvgPrice = minOf(available, func(p SourcePrice) *float64 { return p.AvgPrice })
+			skin.MaxPrice = minOf(available, func(p SourcePrice) *float64 { return p.MaxPrice })
+		case PolicyWeighted:
+			skin.MinPrice = weightedOf(available, weights, func(p SourcePrice) *float64 { return p.MinPrice })
+			skin.AvgPrice = weightedOf(available, weights, func(p SourcePrice) *float64 { return p.AvgPrice })
+			skin.MaxPrice = weightedOf(available, weights, func(p SourcePrice) *float64 { return p.MaxPrice })
+		default:
+			// Берём цены первого по порядку источника, у которого есть минимальная цена
+			for _, price := range available {
+				if price.MinPrice != nil {
+					skin.MinPrice, skin.AvgPrice, skin.MaxPrice = price.MinPrice, price.AvgPrice, price.MaxPrice
+					break
+				}
+			}
+		}
+		skins = append(skins, skin)
+	}
+	return skins
+}
+
+func minOf(prices []SourcePrice, field func(SourcePrice) *float64) *float64 {
+	var result *float64
+	for _, price := range prices {
+		if v := field(price); v != nil && (result == nil || *v < *result) {
+			value := *v
+			result = &value
+		}
+	}
+	return result
+}
+
+func weightedOf(prices []SourcePrice, weights []float64, field func(SourcePrice) *float64) *float64 {
+	var sum, total float64
+	for i, price := range prices {
+		if v := field(price); v != nil && weights[i] > 0 {
+			sum += *v * weights[i]
+			total += weights[i]
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	result := sum / total
+	return &result
+}
@@ -0,0 +1,221 @@
+package listings
+
+import (
+	"cs-market/internal/inventory"
+	"cs-market/internal/steamapi"
+	"cs-market/internal/storage"
+	"cs-market/internal/tradebot"
+	"cs-market/internal/users"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Security BearerAuth
+// CreateListingHandler godoc
+// @Summary Выставление предмета на продажу
+// @Description Создание лота для предмета из Steam инвентаря пользователя. Без торговых ботов продавцу нужен сохранённый ключ Steam Web API
+// @Tags listings
+// @Accept json
+// @Produce json
+// @Param input body CreateListingRequest true "Предмет и цена"
+// @Success 201 {object} Listing "Созданный лот"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 403 {object} response.ErrorResponse "Инвентарь Steam закрыт"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 409 {object} response.ErrorResponse "Предмет уже выставлен на продажу"
+// @Failure 412 {object} response.ErrorResponse "Не указана ссылка на обмен или ключ Steam Web API"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения инвентаря"
+// @Router /listings [post]
+func CreateListingHandler(c *gin.Context) {
+	var input CreateListingRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	seller, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if seller.TradeURL == "" {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Не указана ссылка на обмен"})
+		return
+	}
+	// Без торговых ботов передача проверяется по истории обменов продавца, для неё нужен его ключ
+	if !tradebot.Enabled() && seller.SteamAPIKey == "" {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Не указан ключ Steam Web API"})
+		return
+	}
+
+	// Кеш может хранить предмет, который уже передан, поэтому наличие проверяется по свежему инвентарю
+	inv, err := inventory.FetchInventory(seller.SteamID)
+	if err != nil {
+		if errors.Is(err, steamapi.ErrPrivateInventory) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Инвентарь Steam закрыт"})
+			return
+		}
+		log.Println("Ошибка получения инвентаря:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения инвентаря"})
+		return
+	}
+
+	item, found := inv.FindItem(input.AssetID)
+	if !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Предмет не найден в инвентаре"})
+		return
+	}
+	if !item.Marketable || !item.Tradable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Предмет нельзя продать"})
+		return
+	}
+
+	var count int64
+	storage.DB.Model(&Listing{}).
+		Where("asset_id = ? AND status IN ?", item.AssetID, []string{StatusActive, StatusReserved}).
+		Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Предмет уже выставлен на продажу"})
+		return
+	}
+
+	listing := Listing{
+		AssetID:        item.AssetID,
+		ClassID:        item.ClassID,
+		InstanceID:     item.InstanceID,
+		MarketHashName: item.MarketHashName,
+		IconURL:        item.IconURL,
+		ItemDetails:    item.Details,
+		SellerID:       seller.ID,
+		Price:          input.Price,
+		Status:         StatusActive,
+	}
+	if err := storage.DB.Create(&listing).Error; err != nil {
+		// Параллельный запрос успел выставить тот же предмет
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Предмет уже выставлен на продажу"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания лота"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, listing)
+}
+
+// @Security BearerAuth
+// UpdateListingPriceHandler godoc
+// @Summary Изменение цены лота
+// @Description Изменение цены своего активного лота
+// @Tags listings
+// @Accept json
+// @Produce json
+// @Param id path int true "ID лота"
+// @Param input body UpdatePriceRequest true "Новая цена"
+// @Success 200 {object} Listing "Обновлённый лот"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 404 {object} response.ErrorResponse "Лот не найден"
+// @Router /listings/{id}/price [put]
+func UpdateListingPriceHandler(c *gin.Context) {
+	var input UpdatePriceRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	listing, ok := ownActiveListing(c)
+	if !ok {
+		return
+	}
+
+	if err := storage.DB.Model(&listing).Update("price", input.Price).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления лота"})
+		return
+	}
+	listing.Price = input.Price
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// @Security BearerAuth
+// CancelListingHandler godoc
+// @Summary Снятие лота с продажи
+// @Description Отмена своего активного лота
+// @Tags listings
+// @Accept json
+// @Produce json
+// @Param id path int true "ID лота"
+// @Success 200 {object} response.SuccessResponse "Лот снят с продажи"
+// @Failure 404 {object} response.ErrorResponse "Лот не найден"
+// @Router /listings/{id} [delete]
+func CancelListingHandler(c *gin.Context) {
+	listing, ok := ownActiveListing(c)
+	if !ok {
+		return
+	}
+
+	if err := storage.DB.Model(&listing).Update("status", StatusCancelled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка отмены лота"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Лот снят с продажи"})
+}
+
+// @Security BearerAuth
+// GetMyListingsHandler godoc
+// @Summary Мои лоты
+// @Description Получение списка лотов текущего пользователя
+// @Tags listings
+// @Accept json
+// @Produce json
+// @Success 200 {array} Listing "Список лотов"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /profile/listings [get]
+func GetMyListingsHandler(c *gin.Context) {
+	seller, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var result []Listing
+	if err := storage.DB.Where("seller_id = ?", seller.ID).Order("created_at DESC").Find(&result).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения лотов"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func currentUser(c *gin.Context) (users.User, bool) {
+	var user users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return user, false
+	}
+	return user, true
+}
+
+func ownActiveListing(c *gin.Context) (Listing, bool) {
+	var listing Listing
+
+	seller, ok := currentUser(c)
+	if !ok {
+		return listing, false
+	}
+
+	err := storage.DB.Where("id = ? AND seller_id = ? AND status = ?", c.Param("id"), seller.ID, StatusActive).
+		First(&listing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Лот не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения лота"})
+		}
+		return listing, false
+	}
+	return listing, true
+}
@@ -0,0 +1,221 @@
+package listings
+
+import (
+	"cs-market/internal/storage"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMarketLimit = 50
+	maxMarketLimit     = 100
+)
+
+// discountExpr - скидка относительно цены маркета. Округляется до numeric с фиксированной точностью,
+// чтобы значение в курсоре точно совпадало со значением в БД и страницы не теряли и не дублировали лоты
+const discountExpr = "ROUND((1 - listings.price / NULLIF(skins.min_price, 0))::numeric, 4)"
+
+// Варианты сортировки выдачи: выражение и направление
+var marketSorts = map[string]struct {
+	expr string
+	desc bool
+}{
+	"date_desc":     {"listings.created_at", true},
+	"date_asc":      {"listings.created_at", false},
+	"price_asc":     {"listings.price", false},
+	"price_desc":    {"listings.price", true},
+	"discount_desc": {"COALESCE(" + discountExpr + ", -1000000)", true},
+}
+
+// marketCursor - позиция последнего отданного лота для keyset-пагинации
+type marketCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func encodeCursor(cur marketCursor) string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (marketCursor, error) {
+	var cur marketCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, err
+	}
+	err = json.Unmarshal(data, &cur)
+	return cur, err
+}
+
+// GetMarketHandler godoc
+// @Summary Поиск лотов на маркете
+// @Description Публичная выдача активных лотов с фильтрами, сортировкой и курсорной пагинацией
+// @Tags market
+// @Accept json
+// @Produce json
+// @Param q query string false "Поиск по market_hash_name"
+// @Param min_price query number false "Минимальная цена"
+// @Param max_price query number false "Максимальная цена"
+// @Param exterior query string false "Износ (Factory New, Field-Tested, ...)"
+// @Param rarity query string false "Редкость"
+// @Param type query string false "Тип предмета"
+// @Param weapon query string false "Оружие"
+// @Param quality query string false "Качество"
+// @Param collection query string false "Коллекция"
+// @Param stattrak query bool false "Только StatTrak"
+// @Param souvenir query bool false "Только сувенирные"
+// @Param sort query string false "Сортировка" Enums(date_desc, date_asc, price_asc, price_desc, discount_desc)
+// @Param limit query int false "Количество лотов (до 100)"
+// @Param cursor query string false "Курсор следующей страницы"
+// @Success 200 {object} MarketResponse "Лоты"
+// @Failure 400 {object} response.ErrorResponse "Некорректные параметры"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения лотов"
+// @Router /market [get]
+func GetMarketHandler(c *gin.Context) {
+	sortKey := c.DefaultQuery("sort", "date_desc")
+	sort, ok := marketSorts[sortKey]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестная сортировка"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultMarketLimit)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный limit"})
+		return
+	}
+	if limit > maxMarketLimit {
+		limit = maxMarketLimit
+	}
+
+	query := storage.DB.Model(&Listing{}).
+		Select("listings.*, skins.min_price AS market_price, "+discountExpr+" AS discount").
+		Joins("LEFT JOIN skins ON skins.market_hash_name = listings.market_hash_name").
+		Where("listings.status = ?", StatusActive)
+
+	if q := c.Query("q"); q != "" {
+		query = query.Where("to_tsvector('simple', listings.market_hash_name) @@ plainto_tsquery('simple', ?)", q)
+	}
+	if query, err = applyPriceFilter(c, query, "min_price", "listings.price >= ?"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный min_price"})
+		return
+	}
+	if query, err = applyPriceFilter(c, query, "max_price", "listings.price <= ?"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный max_price"})
+		return
+	}
+	for param, column := range map[string]string{
+		"exterior":   "listings.exterior",
+		"rarity":     "listings.rarity",
+		"type":       "listings.type",
+		"weapon":     "listings.weapon",
+		"quality":    "listings.quality",
+		"collection": "listings.collection",
+	} {
+		if value := c.Query(param); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	for _, param := range []string{"stattrak", "souvenir"} {
+		if value := c.Query(param); value != "" {
+			flag, err := strconv.ParseBool(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр " + param})
+				return
+			}
+			query = query.Where("listings."+param+" = ?", flag)
+		}
+	}
+
+	op, dir := ">", "ASC"
+	if sort.desc {
+		op, dir = "<", "DESC"
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cur, err := decodeCursor(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный курсор"})
+			return
+		}
+		value, err := cursorValue(sortKey, cur.Value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный курсор"})
+			return
+		}
+		query = query.Where("("+sort.expr+" "+op+" ?) OR ("+sort.expr+" = ? AND listings.id "+op+" ?)",
+			value, value, cur.ID)
+	}
+
+	var items []MarketItem
+	err = query.
+		Order(sort.expr + " " + dir).
+		Order("listings.id " + dir).
+		Limit(limit + 1).
+		Scan(&items).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения лотов"})
+		return
+	}
+
+	resp := MarketResponse{Items: items}
+	if len(items) > limit {
+		resp.Items = items[:limit]
+		last := resp.Items[limit-1]
+		resp.NextCursor = encodeCursor(marketCursor{Value: sortValue(sortKey, last), ID: last.ID})
+	}
+	if resp.Items == nil {
+		resp.Items = []MarketItem{}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func applyPriceFilter(c *gin.Context, query *gorm.DB, param, cond string) (*gorm.DB, error) {
+	raw := c.Query(param)
+	if raw == "" {
+		return query, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return query, err
+	}
+	return query.Where(cond, value), nil
+}
+
+// sortValue возвращает значение поля сортировки лота для курсора
+func sortValue(sortKey string, item MarketItem) string {
+	switch sortKey {
+	case "price_asc", "price_desc":
+		return strconv.FormatFloat(item.Price, 'f', -1, 64)
+	case "discount_desc":
+		if item.Discount == nil {
+			return "-1000000"
+		}
+		return strconv.FormatFloat(*item.Discount, 'f', -1, 64)
+	default:
+		return item.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+func cursorValue(sortKey, raw string) (interface{}, error) {
+	switch sortKey {
+	case "price_asc", "price_desc":
+		return strconv.ParseFloat(raw, 64)
+	case "discount_desc":
+		// Скидка сравнивается как numeric, поэтому в запрос передаётся исходная десятичная строка
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	default:
+		return time.Parse(time.RFC3339Nano, raw)
+	}
+}
@@ -0,0 +1,65 @@
+package listings
+
+import (
+	"cs-market/internal/inventory"
+	"cs-market/internal/users"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы лота
+const (
+	StatusActive    = "active"
+	StatusReserved  = "reserved"
+	StatusCancelled = "cancelled"
+	StatusSold      = "sold"
+)
+
+type Listing struct {
+	gorm.Model
+	// AssetID - предмет может быть только в одном активном или зарезервированном лоте
+	AssetID        string `json:"asset_id" gorm:"not null;index;uniqueIndex:idx_listings_open_asset,where:status IN ('active'\\,'reserved')"`
+	ClassID        string `json:"class_id" gorm:"not null"`
+	InstanceID     string `json:"instance_id"`
+	MarketHashName string `json:"market_hash_name" gorm:"not null;index"`
+	IconURL        string `json:"icon_url"`
+	inventory.ItemDetails
+	SellerID uint       `json:"seller_id" gorm:"not null;index"`
+	Seller   users.User `json:"-" gorm:"foreignKey:SellerID"`
+	Price    float64    `json:"price" gorm:"not null"`
+	Status   string     `json:"status" gorm:"not null;default:active;index"`
+}
+
+// MigrateSearchIndex создаёт GIN-индекс полнотекстового поиска по названию, которым пользуется фильтр q маркета.
+// Выражение индекса должно совпадать с выражением в запросе, иначе Postgres его не использует
+func MigrateSearchIndex(db *gorm.DB) error {
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_listings_search ON listings USING GIN (to_tsvector('simple', market_hash_name))").Error
+}
+
+type CreateListingRequest struct {
+	AssetID string  `json:"asset_id" binding:"required"`
+	Price   float64 `json:"price" binding:"required,gt=0"`
+}
+
+type UpdatePriceRequest struct {
+	Price float64 `json:"price" binding:"required,gt=0"`
+}
+
+// MarketItem - лот в публичной выдаче маркета
+type MarketItem struct {
+	ID             uint   `json:"id"`
+	AssetID        string `json:"asset_id"`
+	MarketHashName string `json:"market_hash_name"`
+	IconURL        string `json:"icon_url"`
+	inventory.ItemDetails
+	Price       float64   `json:"price"`
+	MarketPrice *float64  `json:"market_price"`
+	Discount    *float64  `json:"discount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type MarketResponse struct {
+	Items      []MarketItem `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
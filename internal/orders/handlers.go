@@ -0,0 +1,241 @@
+package orders
+
+import (
+	"cs-market/internal/storage"
+	"cs-market/internal/users"
+	"cs-market/internal/wallet"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Security BearerAuth
+// BuyListingHandler godoc
+// @Summary Покупка лота
+// @Description Резервирует средства покупателя и создаёт заказ, продавец должен передать предмет до истечения срока. При P2P-передаче продавцу показывается ссылка на обмен покупателя, а передача проверяется по истории обменов продавца
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "ID лота"
+// @Success 201 {object} Order "Созданный заказ"
+// @Failure 400 {object} response.ErrorResponse "Нельзя купить свой лот"
+// @Failure 402 {object} response.ErrorResponse "Недостаточно средств"
+// @Failure 404 {object} response.ErrorResponse "Лот недоступен для покупки"
+// @Failure 409 {object} response.ErrorResponse "Продавец не может передать предмет"
+// @Failure 412 {object} response.ErrorResponse "Не указана ссылка на обмен"
+// @Failure 500 {object} response.ErrorResponse "Ошибка покупки"
+// @Failure 503 {object} response.ErrorResponse "Торговый бот недоступен, средства возвращены"
+// @Router /market/listings/{id}/buy [post]
+func BuyListingHandler(c *gin.Context) {
+	buyer, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if buyer.TradeURL == "" {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Не указана ссылка на обмен"})
+		return
+	}
+
+	order, err := Buy(storage.DB, c.Param("id"), buyer)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrListingUnavailable):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Лот недоступен для покупки"})
+		case errors.Is(err, ErrOwnListing):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя купить свой лот"})
+		case errors.Is(err, wallet.ErrInsufficientFunds):
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": "Недостаточно средств"})
+		case errors.Is(err, ErrSellerNoAPIKey):
+			c.JSON(http.StatusConflict, gin.H{"error": "Продавец не может передать предмет"})
+		case errors.Is(err, ErrTradeBotFailed):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Торговый бот недоступен, средства возвращены"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка покупки"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// @Security BearerAuth
+// GetMyOrdersHandler godoc
+// @Summary История заказов
+// @Description Получение покупок и продаж пользователя
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param role query string false "Фильтр по роли" Enums(buyer, seller)
+// @Success 200 {array} Order "Заказы"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения заказов"
+// @Router /profile/orders [get]
+func GetMyOrdersHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	query := storage.DB.Order("created_at DESC")
+	switch c.Query("role") {
+	case "buyer":
+		query = query.Where("buyer_id = ?", user.ID)
+	case "seller":
+		query = query.Where("seller_id = ?", user.ID)
+	default:
+		query = query.Where("buyer_id = ? OR seller_id = ?", user.ID, user.ID)
+	}
+
+	var result []Order
+	if err := query.Find(&result).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения заказов"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Security BearerAuth
+// GetOrderHandler godoc
+// @Summary Заказ
+// @Description Получение заказа, в котором пользователь покупатель или продавец
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заказа"
+// @Success 200 {object} Order "Заказ"
+// @Failure 404 {object} response.ErrorResponse "Заказ не найден"
+// @Router /profile/orders/{id} [get]
+func GetOrderHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	order, ok := participantOrder(c, user.ID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// @Security BearerAuth
+// MarkTradeSentHandler godoc
+// @Summary Предмет отправлен
+// @Description Продавец отмечает, что отправил предложение обмена покупателю по ссылке buyer_trade_url. Недоступно для заказов с доставкой через бота
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заказа"
+// @Success 200 {object} Order "Заказ"
+// @Failure 403 {object} response.ErrorResponse "Действие недоступно"
+// @Failure 404 {object} response.ErrorResponse "Заказ не найден"
+// @Failure 409 {object} response.ErrorResponse "Недопустимая смена статуса заказа"
+// @Router /profile/orders/{id}/trade-sent [post]
+func MarkTradeSentHandler(c *gin.Context) {
+	changeStatus(c, StatusTradeSent, func(o Order, userID uint) bool {
+		return o.SellerID == userID && o.Delivery != DeliveryBot
+	})
+}
+
+// @Security BearerAuth
+// ConfirmOrderHandler godoc
+// @Summary Подтверждение получения
+// @Description Покупатель подтверждает получение предмета, заказ переходит под защиту обмена Steam, и средства переводятся продавцу по её окончании, если обмен не откачен. P2P-заказ подтверждается и автоматически, когда обмен появится в истории обменов продавца. Недоступно для заказов с доставкой через бота
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заказа"
+// @Success 200 {object} Order "Заказ"
+// @Failure 403 {object} response.ErrorResponse "Действие недоступно"
+// @Failure 404 {object} response.ErrorResponse "Заказ не найден"
+// @Failure 409 {object} response.ErrorResponse "Недопустимая смена статуса заказа"
+// @Router /profile/orders/{id}/confirm [post]
+func ConfirmOrderHandler(c *gin.Context) {
+	changeStatus(c, StatusProtected, func(o Order, userID uint) bool {
+		return o.BuyerID == userID && o.Delivery != DeliveryBot
+	})
+}
+
+// @Security BearerAuth
+// CancelOrderHandler godoc
+// @Summary Отмена заказа
+// @Description Отмена заказа с возвратом средств покупателю. Покупатель может отменить заказ только до отправки предмета, P2P-заказ покупатель отменить не может - средства возвращаются по истечении срока передачи, если обмен не найден в истории обменов продавца. Заказ с доставкой через бота отменяется только пока предмет не передан боту
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заказа"
+// @Success 200 {object} Order "Заказ"
+// @Failure 403 {object} response.ErrorResponse "Действие недоступно"
+// @Failure 404 {object} response.ErrorResponse "Заказ не найден"
+// @Failure 409 {object} response.ErrorResponse "Недопустимая смена статуса заказа"
+// @Router /profile/orders/{id}/cancel [post]
+func CancelOrderHandler(c *gin.Context) {
+	changeStatus(c, StatusCancelled, func(o Order, userID uint) bool {
+		switch o.Delivery {
+		case DeliveryBot:
+			return o.Status == StatusPendingTrade
+		case DeliveryP2P:
+			// Покупатель мог уже принять обмен, поэтому P2P-заказ без передачи закрывается
+			// только по сроку в VerifyP2POrders, после проверки истории обменов продавца
+			return o.SellerID == userID
+		}
+		return o.SellerID == userID || (o.BuyerID == userID && o.Status == StatusPendingTrade)
+	})
+}
+
+func changeStatus(c *gin.Context, to string, allowed func(o Order, userID uint) bool) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	order, ok := participantOrder(c, user.ID)
+	if !ok {
+		return
+	}
+	if !allowed(order, user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Действие недоступно"})
+		return
+	}
+
+	updated, err := transition(storage.DB, order.ID, to)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Недопустимая смена статуса заказа"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка изменения заказа"})
+		}
+		return
+	}
+	cancelBotOffers(updated)
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func currentUser(c *gin.Context) (users.User, bool) {
+	var user users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return user, false
+	}
+	return user, true
+}
+
+func participantOrder(c *gin.Context, userID uint) (Order, bool) {
+	var order Order
+	err := storage.DB.Where("id = ? AND (buyer_id = ? OR seller_id = ?)", c.Param("id"), userID, userID).
+		First(&order).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Заказ не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения заказа"})
+		}
+		return order, false
+	}
+	return order, true
+}
@@ -0,0 +1,82 @@
+package orders
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы заказа
+const (
+	StatusPendingTrade = "pending_trade"
+	StatusTradeSent    = "trade_sent"
+	// StatusProtected - предмет передан, но Steam может откатить обмен в течение срока защиты.
+	// Средства покупателя остаются замороженными до окончания срока
+	StatusProtected = "protected"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+	// StatusFailed - обмен через бота не состоялся, средства возвращены покупателю
+	StatusFailed = "failed"
+	// StatusDisputed - во время защиты Steam откатил обмен, обмен не удалось проверить, заказ передан в спор поддержкой
+	// или покупатель не подтвердил получение ручного заказа
+	// в срок, средства возвращены покупателю
+	StatusDisputed = "disputed"
+)
+
+// Способы передачи предмета
+const (
+	// DeliveryManual - продавец передаёт предмет сам, покупатель подтверждает получение.
+	// Используется заказами, созданными до появления P2P-проверки
+	DeliveryManual = "manual"
+	// DeliveryP2P - продавец передаёт предмет сам, передача проверяется по его инвентарю и истории обменов
+	DeliveryP2P = "p2p"
+	// DeliveryBot - предмет передаётся через торгового бота, статус меняется по состоянию предложений обмена
+	DeliveryBot = "bot"
+)
+
+// transitions - допустимые переходы между статусами заказа
+var transitions = map[string][]string{
+	StatusPendingTrade: {StatusTradeSent, StatusCancelled, StatusExpired, StatusFailed},
+	StatusTradeSent:    {StatusProtected, StatusCancelled, StatusExpired, StatusFailed, StatusDisputed},
+	StatusProtected:    {StatusCompleted, StatusDisputed},
+}
+
+type Order struct {
+	gorm.Model
+	ListingID        uint      `json:"listing_id" gorm:"not null;index"`
+	BuyerID          uint      `json:"buyer_id" gorm:"not null;index"`
+	SellerID         uint      `json:"seller_id" gorm:"not null;index"`
+	AssetID          string    `json:"asset_id" gorm:"not null"`
+	ClassID          string    `json:"class_id"`
+	InstanceID       string    `json:"instance_id"`
+	MarketHashName   string    `json:"market_hash_name"`
+	IconURL          string    `json:"icon_url"`
+	Price            float64   `json:"price" gorm:"not null"`
+	Fee              float64   `json:"fee" gorm:"not null"`
+	Status           string    `json:"status" gorm:"not null;index"`
+	Delivery         string    `json:"delivery" gorm:"not null;default:manual"`
+	TradeDeadline    time.Time `json:"trade_deadline" gorm:"index"`
+	BuyerTradeURL    string    `json:"buyer_trade_url,omitempty"`
+	DeliveredAssetID string    `json:"delivered_asset_id,omitempty"`
+	// DeliveredTradeID - обмен Steam, которым продавец передал предмет по P2P-заказу. Во время защиты проверяется только он
+	DeliveredTradeID string     `json:"-"`
+	ProtectedUntil   *time.Time `json:"protected_until" gorm:"index"`
+	CompletedAt      *time.Time `json:"completed_at"`
+}
+
+// Reference - идентификатор заказа в журнале кошелька
+func (o *Order) Reference() string {
+	return "order:" + strconv.FormatUint(uint64(o.ID), 10)
+}
+
+// CanTransition проверяет, разрешён ли переход заказа в статус to
+func (o *Order) CanTransition(to string) bool {
+	for _, status := range transitions[o.Status] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package orders
+
+import (
+	"cs-market/internal/listings"
+	"cs-market/internal/tradebot"
+	"cs-market/internal/users"
+	"cs-market/internal/wallet"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrListingUnavailable = errors.New("лот недоступен для покупки")
+	ErrOwnListing         = errors.New("нельзя купить свой лот")
+	ErrInvalidTransition  = errors.New("недопустимая смена статуса заказа")
+	ErrTradeBotFailed     = errors.New("торговый бот не смог запросить предмет у продавца")
+	ErrSellerNoAPIKey     = errors.New("у продавца нет ключа Steam Web API для проверки передачи")
+)
+
+const (
+	defaultTradeDeadline   = 12 * time.Hour
+	defaultFeePercent      = 5.0
+	defaultTradeProtection = 7 * 24 * time.Hour
+)
+
+// TradeDeadline - время, за которое продавец должен передать предмет.
+// Настраивается переменной ORDER_TRADE_DEADLINE (например, "12h")
+func TradeDeadline() time.Duration {
+	return durationEnv("ORDER_TRADE_DEADLINE", defaultTradeDeadline)
+}
+
+// TradeProtection - срок, в течение которого Steam может откатить обмен, и средства продавца удерживаются.
+// Настраивается переменной TRADE_PROTECTION_PERIOD (например, "168h")
+func TradeProtection() time.Duration {
+	return durationEnv("TRADE_PROTECTION_PERIOD", defaultTradeProtection)
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// FeePercent - комиссия площадки с продажи в процентах (MARKET_FEE_PERCENT)
+func FeePercent() float64 {
+	if p, err := strconv.ParseFloat(os.Getenv("MARKET_FEE_PERCENT"), 64); err == nil && p >= 0 && p <= 100 {
+		return p
+	}
+	return defaultFeePercent
+}
+
+// Buy резервирует средства покупателя и создаёт заказ на активный лот.
+// P2P-заказ возможен, только если у продавца есть ключ Steam Web API: по его истории обменов проверяется передача
+func Buy(db *gorm.DB, listingID string, buyer users.User) (*Order, error) {
+	buyerID := buyer.ID
+	delivery := DeliveryP2P
+	if tradebot.Enabled() {
+		delivery = DeliveryBot
+	}
+
+	var order Order
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var listing listings.Listing
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ?", listingID, listings.StatusActive).
+			First(&listing).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrListingUnavailable
+			}
+			return err
+		}
+		if listing.SellerID == buyerID {
+			return ErrOwnListing
+		}
+		if delivery == DeliveryP2P {
+			var seller users.User
+			if err := tx.Select("id", "steam_api_key").First(&seller, listing.SellerID).Error; err != nil {
+				return err
+			}
+			if seller.SteamAPIKey == "" {
+				return ErrSellerNoAPIKey
+			}
+		}
+
+		order = Order{
+			ListingID:      listing.ID,
+			BuyerID:        buyerID,
+			SellerID:       listing.SellerID,
+			AssetID:        listing.AssetID,
+			ClassID:        listing.ClassID,
+			InstanceID:     listing.InstanceID,
+			MarketHashName: listing.MarketHashName,
+			IconURL:        listing.IconURL,
+			Price:          listing.Price,
+			Fee:            math.Round(listing.Price*FeePercent()) / 100,
+			Status:         StatusPendingTrade,
+			Delivery:       delivery,
+			TradeDeadline:  time.Now().Add(TradeDeadline()),
+		}
+		if delivery == DeliveryP2P {
+			order.BuyerTradeURL = buyer.TradeURL
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+
+		if err := wallet.Hold(tx, buyerID, wallet.FromRubles(order.Price), order.Reference()); err != nil {
+			return err
+		}
+
+		return tx.Model(&listing).Update("status", listings.StatusReserved).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Delivery == DeliveryBot {
+		if err := requestDeposit(db, &order); err != nil {
+			log.Printf("Ошибка запроса предмета по заказу %d: %v", order.ID, err)
+			if err := failDeposit(db, order.ID); err != nil {
+				log.Printf("Ошибка отмены заказа %d: %v", order.ID, err)
+			}
+			return nil, ErrTradeBotFailed
+		}
+	}
+	return &order, nil
+}
+
+// failDeposit закрывает заказ, по которому бот не смог запросить предмет. Продавец в этом не виноват,
+// поэтому лот возвращается в продажу
+func failDeposit(db *gorm.DB, orderID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		order, err := Transition(tx, orderID, StatusFailed)
+		if err != nil {
+			return err
+		}
+		return setListingStatus(tx, order.ListingID, listings.StatusActive)
+	})
+}
+
+// Transition переводит заказ в новый статус и проводит связанные с ним движения средств.
+// Должна вызываться внутри транзакции БД
+func Transition(tx *gorm.DB, orderID uint, to string) (*Order, error) {
+	var order Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error
+	if err != nil {
+		return nil, err
+	}
+	if !order.CanTransition(to) {
+		return nil, ErrInvalidTransition
+	}
+
+	updates := map[string]interface{}{"status": to}
+	amount := wallet.FromRubles(order.Price)
+
+	switch to {
+	case StatusProtected:
+		until := time.Now().Add(TradeProtection())
+		updates["protected_until"] = &until
+		order.ProtectedUntil = &until
+	case StatusCompleted:
+		if err := wallet.Settle(tx, order.SellerID, amount, wallet.FromRubles(order.Fee), order.Reference()); err != nil {
+			return nil, err
+		}
+		if err := setListingStatus(tx, order.ListingID, listings.StatusSold); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		updates["completed_at"] = &now
+		order.CompletedAt = &now
+	case StatusCancelled, StatusExpired, StatusFailed, StatusDisputed:
+		if err := wallet.Refund(tx, order.BuyerID, amount, order.Reference()); err != nil {
+			return nil, err
+		}
+		// Если продавец не передал предмет, обмен не состоялся или был откачен, лот снимается, иначе возвращается в продажу
+		listingStatus := listings.StatusActive
+		if to != StatusCancelled {
+			listingStatus = listings.StatusCancelled
+		}
+		if err := setListingStatus(tx, order.ListingID, listingStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Model(&order).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	order.Status = to
+	return &order, nil
+}
+
+// transition выполняет Transition в отдельной транзакции
+func transition(db *gorm.DB, orderID uint, to string) (*Order, error) {
+	var order *Order
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = Transition(tx, orderID, to)
+		return err
+	})
+	return order, err
+}
+
+func setListingStatus(tx *gorm.DB, listingID uint, status string) error {
+	return tx.Model(&listings.Listing{}).Where("id = ?", listingID).Update("status", status).Error
+}
+
+// ExpireOrders отменяет с возвратом средств заказы, по которым продавец не передал предмет в срок.
+// Ручной заказ, отправку по которому продавец отметил, а покупатель не подтвердил, по истечении срока
+// уходит в спор с возвратом средств: передача ничем не подтверждена, и выплачивать продавцу нельзя.
+// P2P-заказы закрываются по результатам проверки передачи в VerifyP2POrders, заказы через бота в trade_sent - по состоянию предложения обмена
+func ExpireOrders(db *gorm.DB) {
+	now := time.Now()
+
+	var ids []uint
+	err := db.Model(&Order{}).
+		Where("status = ? AND trade_deadline < ? AND delivery <> ?", StatusPendingTrade, now, DeliveryP2P).
+		Pluck("id", &ids).Error
+	if err != nil {
+		fmt.Println("Ошибка получения просроченных заказов:", err)
+		return
+	}
+
+	for _, id := range ids {
+		order, err := transition(db, id, StatusExpired)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidTransition) {
+				log.Printf("Ошибка истечения заказа %d: %v", id, err)
+			}
+			continue
+		}
+		cancelBotOffers(order)
+	}
+
+	var sent []uint
+	err = db.Model(&Order{}).
+		Where("status = ? AND trade_deadline < ? AND delivery = ?", StatusTradeSent, now, DeliveryManual).
+		Pluck("id", &sent).Error
+	if err != nil {
+		fmt.Println("Ошибка получения неподтверждённых заказов:", err)
+		return
+	}
+
+	for _, id := range sent {
+		if _, err := transition(db, id, StatusDisputed); err != nil && !errors.Is(err, ErrInvalidTransition) {
+			log.Printf("Ошибка перевода неподтверждённого заказа %d в спор: %v", id, err)
+		}
+	}
+}
+
+func StartOrderExpirer(db *gorm.DB) {
+	go func() {
+		for {
+			ExpireOrders(db)
+			time.Sleep(time.Minute)
+		}
+	}()
+}
@@ -0,0 +1,241 @@
+package orders
+
+import (
+	"context"
+	"cs-market/internal/inventory"
+	"cs-market/internal/steamapi"
+	"cs-market/internal/users"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultP2PVerifyInterval = 2 * time.Minute
+	defaultP2PVerifyGrace    = time.Hour
+	p2pRequestTimeout        = 30 * time.Second
+	// p2pTradeHistoryPage - по сколько обменов запрашивается история продавца
+	p2pTradeHistoryPage = 50
+)
+
+// P2PVerifyInterval - период проверки передачи по P2P-заказам (P2P_VERIFY_INTERVAL)
+func P2PVerifyInterval() time.Duration {
+	return durationEnv("P2P_VERIFY_INTERVAL", defaultP2PVerifyInterval)
+}
+
+// P2PVerifyGrace - сколько после срока передачи ждать, пока Steam отдаст инвентарь и историю обменов (P2P_VERIFY_GRACE)
+func P2PVerifyGrace() time.Duration {
+	return durationEnv("P2P_VERIFY_GRACE", defaultP2PVerifyGrace)
+}
+
+// p2pEvidence - результат проверки передачи по инвентарю и истории обменов продавца.
+// sellerPrivate - продавец закрыл инвентарь или его ключ Steam Web API недействителен (403): это отказ от проверки,
+// в отличие от временных сбоев Steam
+type p2pEvidence struct {
+	sellerChecked  bool
+	sellerPrivate  bool
+	sellerHolds    bool
+	historyChecked bool
+	deliveredAsset string
+	deliveredTrade string
+}
+
+// delivered - в истории обменов продавца есть обмен с покупателем, в котором ушёл предмет лота
+func (e p2pEvidence) delivered() bool {
+	return e.deliveredAsset != ""
+}
+
+// p2pSeller - продавец открытых P2P-заказов. Инвентарь и история обменов загружаются не больше
+// одного раза за проверку, сколько бы заказов у продавца ни было
+type p2pSeller struct {
+	user users.User
+	// since - покупка самого раннего открытого заказа продавца, более ранние обмены не запрашиваются
+	since time.Time
+
+	invLoaded bool
+	inv       *inventory.Inventory
+	invErr    error
+
+	tradesLoaded bool
+	trades       []steamapi.Trade
+	tradesErr    error
+}
+
+func (s *p2pSeller) loadInventory() (*inventory.Inventory, error) {
+	if !s.invLoaded {
+		s.inv, s.invErr = inventory.FetchInventory(s.user.SteamID)
+		s.invLoaded = true
+	}
+	return s.inv, s.invErr
+}
+
+func (s *p2pSeller) loadTrades() ([]steamapi.Trade, error) {
+	if !s.tradesLoaded {
+		s.trades, s.tradesErr = sellerTrades(string(s.user.SteamAPIKey), s.since)
+		s.tradesLoaded = true
+	}
+	return s.trades, s.tradesErr
+}
+
+// collectP2PEvidence проверяет передачу по заказу. Недоступность Steam не считается ошибкой,
+// а отмечается в результате, чтобы решение принималось с учётом того, почему продавца не удалось проверить
+func collectP2PEvidence(db *gorm.DB, seller *p2pSeller, order *Order) (p2pEvidence, error) {
+	var evidence p2pEvidence
+
+	var buyer users.User
+	if err := db.First(&buyer, order.BuyerID).Error; err != nil {
+		return evidence, err
+	}
+
+	if inv, err := seller.loadInventory(); err != nil {
+		evidence.sellerPrivate = errors.Is(err, steamapi.ErrPrivateInventory)
+		log.Printf("Не удалось проверить инвентарь продавца по заказу %d: %v", order.ID, err)
+	} else {
+		evidence.sellerChecked = true
+		evidence.sellerHolds = inv.HasAsset(order.AssetID)
+	}
+	if evidence.sellerHolds {
+		return evidence, nil
+	}
+
+	// Такой же classid и instanceid в инвентаре покупателя передачу не доказывает: это может быть другой экземпляр,
+	// поэтому нужен завершённый обмен с предметом лота в истории обменов продавца
+	trades, err := seller.loadTrades()
+	if err != nil {
+		evidence.sellerPrivate = evidence.sellerPrivate || errors.Is(err, steamapi.ErrInvalidAPIKey)
+		log.Printf("Не удалось получить историю обменов продавца по заказу %d: %v", order.ID, err)
+		return evidence, nil
+	}
+	evidence.historyChecked = true
+	if trade, delivered, ok := p2pTrade(trades, buyer.SteamID, order); ok && trade.Status == steamapi.TradeStatusComplete {
+		evidence.deliveredAsset = delivered
+		evidence.deliveredTrade = trade.TradeID
+	}
+	return evidence, nil
+}
+
+// sellerTrades возвращает обмены продавца, начатые не раньше since, по его ключу Steam Web API
+func sellerTrades(apiKey string, since time.Time) ([]steamapi.Trade, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p2pRequestTimeout)
+	defer cancel()
+	return steamapi.Default().TradeHistorySince(ctx, apiKey, since, p2pTradeHistoryPage)
+}
+
+// p2pTrade находит в истории обмен с покупателем после покупки, в котором продавец отдал именно предмет лота,
+// и assetid, полученный предметом у покупателя. Состояние обмена не проверяется
+func p2pTrade(trades []steamapi.Trade, buyerSteamID string, order *Order) (steamapi.Trade, string, bool) {
+	for _, trade := range trades {
+		if trade.SteamIDOther != buyerSteamID || trade.TimeInit < order.CreatedAt.Unix() {
+			continue
+		}
+		for _, asset := range trade.AssetsGiven {
+			if asset.AssetID == order.AssetID && asset.NewAssetID != "" {
+				return trade, asset.NewAssetID, true
+			}
+		}
+	}
+	return steamapi.Trade{}, "", false
+}
+
+// verifyP2POrder закрывает P2P-заказ по результатам проверки: обмен с предметом лота найден - protected;
+// срок вышел, а предмет у продавца или ушёл не покупателю - expired с возвратом.
+// Если по окончании срока и ожидания P2P_VERIFY_GRACE продавец закрыл инвентарь или его ключ недействителен,
+// передача не доказана, и средства возвращаются покупателю.
+// При временных сбоях Steam (429, 5xx, таймаут) решение не принимается, проверка повторяется
+func verifyP2POrder(db *gorm.DB, seller *p2pSeller, order *Order) {
+	evidence, err := collectP2PEvidence(db, seller, order)
+	if err != nil {
+		log.Printf("Ошибка проверки заказа %d: %v", order.ID, err)
+		return
+	}
+
+	now := time.Now()
+	closed := now.After(order.TradeDeadline)
+	final := now.After(order.TradeDeadline.Add(P2PVerifyGrace()))
+
+	switch {
+	case evidence.delivered():
+		err = deliverP2P(db, order.ID, evidence.deliveredTrade, evidence.deliveredAsset)
+	case !closed:
+		return
+	case evidence.sellerHolds || (evidence.sellerChecked && evidence.historyChecked):
+		_, err = transition(db, order.ID, StatusExpired)
+	case final && evidence.sellerPrivate:
+		_, err = transition(db, order.ID, StatusExpired)
+	default:
+		return
+	}
+	if err != nil && !errors.Is(err, ErrInvalidTransition) {
+		log.Printf("Ошибка закрытия заказа %d по проверке передачи: %v", order.ID, err)
+	}
+}
+
+// deliverP2P засчитывает передачу предмета обменом tradeID и переводит заказ под защиту обмена.
+// Если продавец не отметил отправку, заказ проходит через trade_sent
+func deliverP2P(db *gorm.DB, orderID uint, tradeID, deliveredAsset string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return err
+		}
+		if order.Status == StatusPendingTrade {
+			if _, err := Transition(tx, orderID, StatusTradeSent); err != nil {
+				return err
+			}
+		}
+		protected, err := Transition(tx, orderID, StatusProtected)
+		if err != nil {
+			return err
+		}
+		return tx.Model(protected).Updates(map[string]interface{}{
+			"delivered_trade_id": tradeID,
+			"delivered_asset_id": deliveredAsset,
+		}).Error
+	})
+}
+
+// VerifyP2POrders проверяет передачу предметов по открытым P2P-заказам. Steam запрашивается
+// по продавцам, а не по заказам, чтобы несколько заказов одного продавца не расходовали лимит запросов
+func VerifyP2POrders(db *gorm.DB) {
+	var pending []Order
+	err := db.Where("delivery = ? AND status IN ?", DeliveryP2P, []string{StatusPendingTrade, StatusTradeSent}).
+		Order("trade_deadline").
+		Find(&pending).Error
+	if err != nil {
+		log.Println("Ошибка получения P2P-заказов:", err)
+		return
+	}
+
+	sellers := make(map[uint]*p2pSeller)
+	for _, order := range pending {
+		if seller, ok := sellers[order.SellerID]; ok {
+			if order.CreatedAt.Before(seller.since) {
+				seller.since = order.CreatedAt
+			}
+			continue
+		}
+		seller := &p2pSeller{since: order.CreatedAt}
+		if err := db.First(&seller.user, order.SellerID).Error; err != nil {
+			log.Printf("Ошибка получения продавца заказа %d: %v", order.ID, err)
+			seller = nil
+		}
+		sellers[order.SellerID] = seller
+	}
+
+	for i := range pending {
+		if seller := sellers[pending[i].SellerID]; seller != nil {
+			verifyP2POrder(db, seller, &pending[i])
+		}
+	}
+}
+
+func StartP2PVerifier(db *gorm.DB) {
+	go func() {
+		for {
+			VerifyP2POrders(db)
+			time.Sleep(P2PVerifyInterval())
+		}
+	}()
+}
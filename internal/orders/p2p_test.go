@@ -0,0 +1,301 @@
+package orders
+
+import (
+	"cs-market/internal/listings"
+	"cs-market/internal/steamapi"
+	"cs-market/internal/steamapi/steamapitest"
+	"cs-market/internal/tradebot"
+	"cs-market/internal/users"
+	"cs-market/internal/wallet"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	sellerAPIKey   = "0123456789ABCDEF0123456789ABCDEF"
+	p2pSellerID    = "76561197960266729"
+	p2pBuyerID     = "76561197960266730"
+	p2pClassID     = "310776"
+	p2pInstanceID  = "302028390"
+	deliveredAsset = "999"
+)
+
+// p2pFlow - P2P-заказ без торговых ботов, Steam заменён сервером steamapitest
+type p2pFlow struct {
+	t      *testing.T
+	db     *gorm.DB
+	steam  *steamapitest.Server
+	seller users.User
+	buyer  users.User
+	order  *Order
+}
+
+func newP2PFlow(t *testing.T) *p2pFlow {
+	db := testDB(t)
+	tradebot.SetDefault(nil)
+
+	steam := steamapitest.NewServer()
+	t.Cleanup(steam.Close)
+	steamapi.SetDefault(steam.Client())
+	t.Cleanup(func() { steamapi.SetDefault(nil) })
+	t.Setenv("INVENTORY_PAGE_DELAY", "1ms")
+
+	f := &p2pFlow{t: t, db: db, steam: steam}
+	f.seller = f.user(p2pSellerID, sellerAPIKey)
+	f.buyer = f.user(p2pBuyerID, "")
+	f.setInventory(p2pSellerID, testAssetID)
+	steam.AddTrades(sellerAPIKey)
+
+	listing := listings.Listing{
+		AssetID:        testAssetID,
+		ClassID:        p2pClassID,
+		InstanceID:     p2pInstanceID,
+		MarketHashName: "AK-47 | Redline (Field-Tested)",
+		SellerID:       f.seller.ID,
+		Price:          testPrice,
+		Status:         listings.StatusActive,
+	}
+	if err := db.Create(&listing).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return wallet.Deposit(tx, f.buyer.ID, wallet.FromRubles(testPrice), "test:deposit")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.order, err = Buy(db, strconv.FormatUint(uint64(listing.ID), 10), f.buyer)
+	if err != nil {
+		t.Fatalf("Buy: %v", err)
+	}
+	if f.order.Delivery != DeliveryP2P {
+		t.Fatalf("delivery = %s, want %s", f.order.Delivery, DeliveryP2P)
+	}
+	return f
+}
+
+func (f *p2pFlow) user(steamID, apiKey string) users.User {
+	accountID, _ := strconv.ParseUint(steamID, 10, 64)
+	user := users.User{
+		SteamID:     steamID,
+		TradeURL:    "https://steamcommunity.com/tradeoffer/new/?partner=" + strconv.FormatUint(accountID-76561197960265728, 10) + "&token=tokenTKN",
+		SteamAPIKey: users.EncryptedString(apiKey),
+	}
+	if err := f.db.Create(&user).Error; err != nil {
+		f.t.Fatal(err)
+	}
+	var stored string
+	f.db.Model(&users.User{}).Where("id = ?", user.ID).Select("steam_api_key").Scan(&stored)
+	if apiKey != "" && strings.Contains(stored, apiKey) {
+		f.t.Fatal("ключ Steam Web API сохранён в открытом виде")
+	}
+	f.steam.AddPlayer(steamID)
+	return user
+}
+
+// setInventory кладёт в инвентарь предметы того же classid и instanceid, что у лота
+func (f *p2pFlow) setInventory(steamID string, assetIDs ...string) {
+	f.steam.UpdatePlayer(steamID, func(p *steamapitest.Player) {
+		p.Assets = nil
+		for _, id := range assetIDs {
+			p.Assets = append(p.Assets, json.RawMessage(`{"appid":730,"contextid":"2","assetid":"`+id+`","classid":"`+p2pClassID+`","instanceid":"`+p2pInstanceID+`","amount":"1"}`))
+		}
+		p.Descriptions = []json.RawMessage{json.RawMessage(`{"classid":"` + p2pClassID + `","instanceid":"` + p2pInstanceID + `","market_hash_name":"AK-47 | Redline (Field-Tested)","tradable":1,"marketable":1}`)}
+	})
+}
+
+// tradeToBuyer - обмен в истории продавца, в котором предмет лота ушёл покупателю
+func (f *p2pFlow) tradeToBuyer(assetID string) steamapi.Trade {
+	return steamapi.Trade{
+		TradeID:      "5001",
+		SteamIDOther: p2pBuyerID,
+		TimeInit:     time.Now().Unix(),
+		Status:       steamapi.TradeStatusComplete,
+		AssetsGiven: []steamapi.TradeAsset{{
+			AppID: 730, ContextID: "2", AssetID: assetID, ClassID: p2pClassID, InstanceID: p2pInstanceID,
+			NewAssetID: deliveredAsset, NewContextID: "2",
+		}},
+	}
+}
+
+// overdue сдвигает срок передачи в прошлое, afterGrace - и ожидание P2P_VERIFY_GRACE тоже
+func (f *p2pFlow) overdue(afterGrace bool) {
+	deadline := time.Now().Add(-time.Minute)
+	if afterGrace {
+		deadline = deadline.Add(-P2PVerifyGrace())
+	}
+	if err := f.db.Model(f.order).Update("trade_deadline", deadline).Error; err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *p2pFlow) verify(status string) Order {
+	VerifyP2POrders(f.db)
+	return f.expectStatus(status)
+}
+
+func (f *p2pFlow) expectStatus(status string) Order {
+	var order Order
+	if err := f.db.First(&order, f.order.ID).Error; err != nil {
+		f.t.Fatal(err)
+	}
+	if order.Status != status {
+		f.t.Fatalf("статус заказа %s, want %s", order.Status, status)
+	}
+	return order
+}
+
+func TestP2PDeliveredByTradeHistory(t *testing.T) {
+	f := newP2PFlow(t)
+	f.verify(StatusPendingTrade)
+
+	f.setInventory(p2pSellerID)
+	f.setInventory(p2pBuyerID, "500", deliveredAsset)
+	f.steam.AddTrades(sellerAPIKey, f.tradeToBuyer(testAssetID))
+
+	order := f.verify(StatusProtected)
+	if order.DeliveredAssetID != deliveredAsset {
+		t.Errorf("delivered_asset_id = %s, want %s", order.DeliveredAssetID, deliveredAsset)
+	}
+}
+
+func TestP2PSameClassWithoutTradeNotDelivered(t *testing.T) {
+	f := newP2PFlow(t)
+	// Предмет лота ушёл другому пользователю, а покупатель получил от продавца такой же предмет другим экземпляром
+	f.setInventory(p2pSellerID)
+	f.setInventory(p2pBuyerID, deliveredAsset)
+	other := f.tradeToBuyer("222")
+	other.TradeID = "5002"
+	f.steam.AddTrades(sellerAPIKey, other, steamapi.Trade{
+		TradeID: "5003", SteamIDOther: "76561197960299999", TimeInit: time.Now().Unix(), Status: steamapi.TradeStatusComplete,
+		AssetsGiven: []steamapi.TradeAsset{{AppID: 730, ContextID: "2", AssetID: testAssetID, NewAssetID: "888"}},
+	})
+	f.verify(StatusPendingTrade)
+
+	f.overdue(false)
+	f.verify(StatusExpired)
+}
+
+func TestP2PTransientErrorsRetried(t *testing.T) {
+	f := newP2PFlow(t)
+	f.setInventory(p2pSellerID)
+	f.overdue(true)
+
+	// Все попытки запросов инвентаря и истории обменов получают 503
+	f.steam.Fail(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable,
+		http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	f.verify(StatusPendingTrade)
+
+	f.steam.AddTrades(sellerAPIKey, f.tradeToBuyer(testAssetID))
+	f.verify(StatusProtected)
+}
+
+func TestP2PPrivateSellerAfterGrace(t *testing.T) {
+	f := newP2PFlow(t)
+	// Продавец закрыл инвентарь и отозвал ключ Steam Web API
+	f.steam.UpdatePlayer(p2pSellerID, func(p *steamapitest.Player) { p.PrivateInventory = true })
+	if err := f.db.Model(&f.seller).Update("steam_api_key", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	f.overdue(false)
+	f.verify(StatusPendingTrade)
+
+	f.overdue(true)
+	f.verify(StatusExpired)
+	account, err := wallet.UserAccount(f.db, f.buyer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != wallet.FromRubles(testPrice) {
+		t.Errorf("баланс покупателя %d коп., want %d", account.Balance, wallet.FromRubles(testPrice))
+	}
+}
+
+func TestP2PRolledBack(t *testing.T) {
+	f := newP2PFlow(t)
+	f.setInventory(p2pSellerID)
+	trade := f.tradeToBuyer(testAssetID)
+	f.steam.AddTrades(sellerAPIKey, trade)
+	if order := f.verify(StatusProtected); order.DeliveredTradeID != trade.TradeID {
+		t.Fatalf("delivered_trade_id = %s, want %s", order.DeliveredTradeID, trade.TradeID)
+	}
+
+	// Под защитой проверяется только обмен заказа, одним запросом
+	before := f.steam.Requests()
+	CheckProtectedOrders(f.db)
+	f.expectStatus(StatusProtected)
+	if n := f.steam.Requests() - before; n != 1 {
+		t.Errorf("запросов к Steam при проверке защиты %d, want 1", n)
+	}
+
+	// Steam откатывает обмен: предмет возвращается продавцу под новым assetid, в истории меняется состояние обмена
+	f.setInventory(p2pSellerID, "1234")
+	trade.Status = steamapi.TradeStatusProtectionRollback
+	f.steam.AddTrades(sellerAPIKey, trade)
+	CheckProtectedOrders(f.db)
+	f.expectStatus(StatusDisputed)
+}
+
+func TestP2PSellerCheckedOncePerRun(t *testing.T) {
+	f := newP2PFlow(t)
+	f.setInventory(p2pSellerID)
+
+	// Второй заказ у того же продавца
+	listing := listings.Listing{
+		AssetID: "777", ClassID: p2pClassID, InstanceID: p2pInstanceID,
+		MarketHashName: "AK-47 | Redline (Field-Tested)",
+		SellerID:       f.seller.ID, Price: testPrice, Status: listings.StatusActive,
+	}
+	if err := f.db.Create(&listing).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		return wallet.Deposit(tx, f.buyer.ID, wallet.FromRubles(testPrice), "test:deposit2")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Buy(f.db, strconv.FormatUint(uint64(listing.ID), 10), f.buyer); err != nil {
+		t.Fatalf("Buy: %v", err)
+	}
+
+	before := f.steam.Requests()
+	f.verify(StatusPendingTrade)
+	// Инвентарь и история обменов продавца - по одному запросу на оба заказа
+	if n := f.steam.Requests() - before; n != 2 {
+		t.Errorf("запросов к Steam %d, want 2", n)
+	}
+}
+
+func TestP2PProtectionUnverifiableDisputed(t *testing.T) {
+	f := newP2PFlow(t)
+	f.setInventory(p2pSellerID)
+	f.steam.AddTrades(sellerAPIKey, f.tradeToBuyer(testAssetID))
+	f.verify(StatusProtected)
+
+	// Продавец отозвал ключ Steam Web API: до окончания защиты и ожидания выплата просто откладывается
+	if err := f.db.Model(&f.seller).Update("steam_api_key", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF").Error; err != nil {
+		t.Fatal(err)
+	}
+	ended := time.Now().Add(-time.Minute)
+	if err := f.db.Model(f.order).Update("protected_until", ended).Error; err != nil {
+		t.Fatal(err)
+	}
+	CheckProtectedOrders(f.db)
+	f.expectStatus(StatusProtected)
+
+	ended = ended.Add(-ProtectionVerifyGrace())
+	if err := f.db.Model(f.order).Update("protected_until", ended).Error; err != nil {
+		t.Fatal(err)
+	}
+	CheckProtectedOrders(f.db)
+	f.expectStatus(StatusDisputed)
+}
@@ -0,0 +1,167 @@
+package orders
+
+import (
+	"context"
+	"cs-market/internal/steamapi"
+	"cs-market/internal/tradebot"
+	"cs-market/internal/users"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultProtectionCheckInterval = 10 * time.Minute
+	defaultProtectionVerifyGrace   = 24 * time.Hour
+	protectionRequestTimeout       = 30 * time.Second
+)
+
+// errTradeNotFound - обмен по P2P-заказу не найден в истории обменов продавца
+var errTradeNotFound = errors.New("обмен по заказу не найден в истории обменов продавца")
+
+// ProtectionCheckInterval - период проверки заказов под защитой обмена (PROTECTION_CHECK_INTERVAL)
+func ProtectionCheckInterval() time.Duration {
+	return durationEnv("PROTECTION_CHECK_INTERVAL", defaultProtectionCheckInterval)
+}
+
+// ProtectionVerifyGrace - сколько после окончания защиты повторять проверку обмена,
+// прежде чем передать непроверенный заказ в спор (PROTECTION_VERIFY_GRACE)
+func ProtectionVerifyGrace() time.Duration {
+	return durationEnv("PROTECTION_VERIFY_GRACE", defaultProtectionVerifyGrace)
+}
+
+// checkProtectedOrder проверяет, не откатил ли Steam обмен. Если откатил - заказ переходит в disputed
+// с возвратом средств покупателю, иначе по окончании защиты средства выплачиваются продавцу.
+// Пока обмен не удаётся проверить, выплата откладывается, а если проверить его не удалось и через
+// PROTECTION_VERIFY_GRACE после окончания защиты (продавец отозвал ключ, обмен не найден), заказ уходит в спор
+func checkProtectedOrder(db *gorm.DB, order *Order) {
+	rolledBack, err := tradeRolledBack(db, order)
+
+	to := StatusCompleted
+	switch {
+	case err != nil:
+		log.Printf("Не удалось проверить обмен по заказу %d под защитой обмена: %v", order.ID, err)
+		if order.ProtectedUntil == nil || time.Now().Before(order.ProtectedUntil.Add(ProtectionVerifyGrace())) {
+			return
+		}
+		log.Printf("Обмен по заказу %d не проверен после окончания защиты, заказ передан в спор", order.ID)
+		to = StatusDisputed
+	case rolledBack:
+		log.Printf("Обмен по заказу %d откачен, предмет %s вернулся к отправителю", order.ID, order.AssetID)
+		to = StatusDisputed
+	case order.ProtectedUntil != nil && time.Now().Before(*order.ProtectedUntil):
+		return
+	}
+
+	if _, err := transition(db, order.ID, to); err != nil && !errors.Is(err, ErrInvalidTransition) {
+		log.Printf("Ошибка закрытия заказа %d после защиты обмена: %v", order.ID, err)
+	}
+}
+
+// tradeRolledBack проверяет обмен по заказу. После отката предмет возвращается с новым assetid,
+// поэтому заказы через бота и P2P-заказы проверяются по состоянию самого обмена в Steam.
+// По ручным заказам обмен неизвестен, и откат не выявляется: покупатель обращается в поддержку,
+// и заказ передаётся в спор через /admin/orders/{id}/dispute
+func tradeRolledBack(db *gorm.DB, order *Order) (bool, error) {
+	switch order.Delivery {
+	case DeliveryBot:
+		return botTradeRolledBack(db, order)
+	case DeliveryP2P:
+		return p2pTradeRolledBack(db, order)
+	}
+	return false, nil
+}
+
+// botTradeRolledBack проверяет обмен принятого предложения доставки через бота, который его отправил
+func botTradeRolledBack(db *gorm.DB, order *Order) (bool, error) {
+	manager := tradebot.Default()
+	if manager == nil {
+		return false, tradebot.ErrNoBots
+	}
+
+	var offer tradebot.TradeOffer
+	err := db.Where("order_id = ? AND kind = ? AND state = ?", order.ID, tradebot.KindDelivery, tradebot.OfferStateAccepted).
+		Order("id DESC").
+		First(&offer).Error
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), protectionRequestTimeout)
+	defer cancel()
+	status, err := manager.TradeStatus(ctx, offer.BotSteamID, offer.TradeID)
+	if err != nil {
+		return false, err
+	}
+	if !steamapi.TradeRolledBack(status.Status) {
+		return false, nil
+	}
+	log.Printf("Предмет по заказу %d вернулся боту %s, продавцу его нужно вернуть вручную", order.ID, offer.BotSteamID)
+	return true, nil
+}
+
+// p2pTradeRolledBack проверяет состояние обмена, которым предмет передан покупателю, одним запросом по ключу продавца.
+// Если заказ подтвердил покупатель и обмен ещё не известен, он один раз ищется в истории обменов продавца и запоминается
+func p2pTradeRolledBack(db *gorm.DB, order *Order) (bool, error) {
+	var seller users.User
+	if err := db.First(&seller, order.SellerID).Error; err != nil {
+		return false, err
+	}
+	apiKey := string(seller.SteamAPIKey)
+
+	if order.DeliveredTradeID == "" {
+		var buyer users.User
+		if err := db.First(&buyer, order.BuyerID).Error; err != nil {
+			return false, err
+		}
+		trades, err := sellerTrades(apiKey, order.CreatedAt)
+		if err != nil {
+			return false, err
+		}
+		trade, delivered, ok := p2pTrade(trades, buyer.SteamID, order)
+		if !ok {
+			return false, errTradeNotFound
+		}
+		err = db.Model(order).Updates(map[string]interface{}{
+			"delivered_trade_id": trade.TradeID,
+			"delivered_asset_id": delivered,
+		}).Error
+		if err != nil {
+			return false, err
+		}
+		return steamapi.TradeRolledBack(trade.Status), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), protectionRequestTimeout)
+	defer cancel()
+	trade, err := steamapi.Default().TradeStatus(ctx, apiKey, order.DeliveredTradeID)
+	if err != nil {
+		return false, err
+	}
+	return steamapi.TradeRolledBack(trade.Status), nil
+}
+
+// CheckProtectedOrders проверяет заказы под защитой обмена: выявляет откаченные обмены
+// и выплачивает средства продавцам по заказам, срок защиты которых истёк
+func CheckProtectedOrders(db *gorm.DB) {
+	var protected []Order
+	if err := db.Where("status = ?", StatusProtected).Order("protected_until").Find(&protected).Error; err != nil {
+		log.Println("Ошибка получения заказов под защитой обмена:", err)
+		return
+	}
+
+	for i := range protected {
+		checkProtectedOrder(db, &protected[i])
+	}
+}
+
+func StartProtectionMonitor(db *gorm.DB) {
+	go func() {
+		for {
+			CheckProtectedOrders(db)
+			time.Sleep(ProtectionCheckInterval())
+		}
+	}()
+}
@@ -0,0 +1,169 @@
+package orders
+
+import (
+	"context"
+	"cs-market/internal/storage"
+	"cs-market/internal/tradebot"
+	"cs-market/internal/users"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const botRequestTimeout = 30 * time.Second
+
+// requestDeposit просит продавца передать боту проданный предмет
+func requestDeposit(db *gorm.DB, order *Order) error {
+	var seller users.User
+	if err := db.First(&seller, order.SellerID).Error; err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), botRequestTimeout)
+	defer cancel()
+	_, err := tradebot.Default().RequestDeposit(ctx, order.ID, seller.TradeURL, order.AssetID)
+	return err
+}
+
+// cancelBotOffers отзывает предложения бота по завершённому без обмена заказу
+func cancelBotOffers(order *Order) {
+	if order.Delivery != DeliveryBot || !tradebot.Enabled() || order.Status == StatusProtected || order.Status == StatusCompleted {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), botRequestTimeout)
+	defer cancel()
+	if err := tradebot.Default().CancelOrderOffers(ctx, order.ID); err != nil {
+		log.Printf("Ошибка отзыва предложений по заказу %d: %v", order.ID, err)
+	}
+}
+
+// HandleTradeOffer переводит заказ по состоянию предложения обмена бота:
+// предмет получен от продавца - trade_sent и отправка покупателю, предмет получен покупателем - protected,
+// обмен не состоялся - failed с возвратом средств и, если предмет у бота, возвратом его продавцу.
+// Временная ошибка отправки покупателю возвращается, чтобы опрос повторил обработку; failed - только при отказе Steam
+func HandleTradeOffer(ctx context.Context, offer tradebot.TradeOffer) error {
+	switch offer.Kind {
+	case tradebot.KindDeposit:
+		if offer.Failed() {
+			return ignoreInvalid(transitionErr(offer.OrderID, StatusFailed))
+		}
+		if !offer.Accepted() {
+			return nil
+		}
+
+		order, err := transition(storage.DB, offer.OrderID, StatusTradeSent)
+		if errors.Is(err, ErrInvalidTransition) {
+			order, err = awaitingDelivery(offer.OrderID)
+			if errors.Is(err, ErrInvalidTransition) {
+				// Заказ уже закрыт, а продавец передал предмет - возвращаем его
+				return returnItem(ctx, offer, offer.NewAssetID)
+			}
+			if errors.Is(err, errDeliverySent) {
+				return nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		var buyer users.User
+		if err := storage.DB.First(&buyer, order.BuyerID).Error; err != nil {
+			return err
+		}
+		_, err = tradebot.Default().SendDelivery(ctx, order.ID, offer.BotSteamID, buyer.TradeURL, offer.NewAssetID)
+		if err != nil {
+			log.Printf("Ошибка доставки по заказу %d: %v", order.ID, err)
+			if !deliveryRejected(err) {
+				// Временная ошибка: заказ остаётся в trade_sent, опрос повторит отправку
+				return err
+			}
+			if err := ignoreInvalid(transitionErr(order.ID, StatusFailed)); err != nil {
+				return err
+			}
+			return returnItem(ctx, offer, offer.NewAssetID)
+		}
+		return nil
+
+	case tradebot.KindDelivery:
+		if offer.Accepted() {
+			return ignoreInvalid(transitionErr(offer.OrderID, StatusProtected))
+		}
+		if !offer.Failed() {
+			return nil
+		}
+		if err := ignoreInvalid(transitionErr(offer.OrderID, StatusFailed)); err != nil {
+			return err
+		}
+		return returnItem(ctx, offer, offer.AssetID)
+
+	case tradebot.KindReturn:
+		if offer.Failed() {
+			log.Printf("Продавец не принял возврат предмета по заказу %d, предложение %s", offer.OrderID, offer.OfferID)
+		}
+	}
+	return nil
+}
+
+// errDeliverySent - доставка по заказу уже отправлена, повторно обрабатывать депозит не нужно
+var errDeliverySent = errors.New("доставка по заказу уже отправлена")
+
+// deliveryRejected сообщает, что Steam окончательно отказал в доставке покупателю и повтор не поможет
+func deliveryRejected(err error) bool {
+	return errors.Is(err, tradebot.ErrBadTradeToken) ||
+		errors.Is(err, tradebot.ErrItemsNotFound) ||
+		errors.Is(err, users.ErrInvalidTradeURL)
+}
+
+// awaitingDelivery возвращает заказ в trade_sent, которому ещё не отправлена доставка, - так бывает,
+// если обработка принятого депозита повторяется после ошибки. Если доставка уже отправлена, возвращает
+// errDeliverySent, а если заказ не в trade_sent - ErrInvalidTransition
+func awaitingDelivery(orderID uint) (*Order, error) {
+	var order Order
+	if err := storage.DB.First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	if order.Status != StatusTradeSent {
+		return nil, ErrInvalidTransition
+	}
+
+	var sent int64
+	err := storage.DB.Model(&tradebot.TradeOffer{}).
+		Where("order_id = ? AND kind = ?", orderID, tradebot.KindDelivery).
+		Count(&sent).Error
+	if err != nil {
+		return nil, err
+	}
+	if sent > 0 {
+		return nil, errDeliverySent
+	}
+	return &order, nil
+}
+
+// returnItem отправляет продавцу предмет, оставшийся у бота
+func returnItem(ctx context.Context, offer tradebot.TradeOffer, assetID string) error {
+	var order Order
+	if err := storage.DB.First(&order, offer.OrderID).Error; err != nil {
+		return err
+	}
+	var seller users.User
+	if err := storage.DB.First(&seller, order.SellerID).Error; err != nil {
+		return err
+	}
+	_, err := tradebot.Default().SendReturn(ctx, order.ID, offer.BotSteamID, seller.TradeURL, assetID)
+	return err
+}
+
+func transitionErr(orderID uint, to string) error {
+	_, err := transition(storage.DB, orderID, to)
+	return err
+}
+
+func ignoreInvalid(err error) error {
+	if errors.Is(err, ErrInvalidTransition) {
+		return nil
+	}
+	return err
+}
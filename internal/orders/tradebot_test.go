@@ -0,0 +1,394 @@
+package orders
+
+import (
+	"context"
+	"cs-market/internal/inventory"
+	"cs-market/internal/listings"
+	"cs-market/internal/steamguard"
+	"cs-market/internal/storage"
+	"cs-market/internal/tradebot"
+	"cs-market/internal/users"
+	"cs-market/internal/wallet"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	testSecret  = "MTIzNDU2Nzg5MDEyMzQ1Njc4OTA="
+	testBotID   = "76561198000000001"
+	testPrice   = 100.0
+	testAssetID = "111"
+)
+
+// testDB подключается к отдельной базе TEST_DB_DSN и очищает таблицы заказов. Без неё тест пропускается
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN не задан")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.SetSecretKey([]byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&users.User{}, &listings.Listing{}, &inventory.Skin{}, &inventory.InventoryCache{},
+		&wallet.Account{}, &wallet.Transaction{}, &wallet.Entry{},
+		&Order{}, &tradebot.TradeOffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec("TRUNCATE trade_offers, orders, listings, inventory_caches, skins, entries, transactions, accounts, users RESTART IDENTITY CASCADE").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage.DB = db
+	return db
+}
+
+// botFlow - продавец, покупатель с оплаченным балансом и лот, доставляемый ботом через FakeEconService
+type botFlow struct {
+	t       *testing.T
+	db      *gorm.DB
+	econ    *tradebot.FakeEconService
+	manager *tradebot.Manager
+	seller  users.User
+	buyer   users.User
+	listing listings.Listing
+}
+
+func newBotFlow(t *testing.T, handler tradebot.Handler) *botFlow {
+	db := testDB(t)
+	econ := tradebot.NewFakeEconService()
+	cfg := tradebot.BotConfig{Name: "test", SteamID: testBotID, SharedSecret: testSecret, IdentitySecret: testSecret, DeviceID: "android:test"}
+	econ.AddBot(cfg)
+
+	if handler == nil {
+		handler = HandleTradeOffer
+	}
+	manager := tradebot.NewManager(db, econ, []*tradebot.Bot{tradebot.NewBot(cfg, steamguard.NewClock())}, handler)
+	tradebot.SetDefault(manager)
+	t.Cleanup(func() { tradebot.SetDefault(nil) })
+
+	f := &botFlow{t: t, db: db, econ: econ, manager: manager}
+	f.seller = f.user(1001, "sellerTK", testAssetID)
+	f.buyer = f.user(1002, "buyerTKN")
+
+	f.listing = listings.Listing{
+		AssetID:        testAssetID,
+		ClassID:        "310776",
+		MarketHashName: "AK-47 | Redline (Field-Tested)",
+		SellerID:       f.seller.ID,
+		Price:          testPrice,
+		Status:         listings.StatusActive,
+	}
+	if err := db.Create(&f.listing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return wallet.Deposit(tx, f.buyer.ID, wallet.FromRubles(testPrice), "test:deposit")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// user создаёт пользователя со ссылкой на обмен и такой же аккаунт в фейковом Steam
+func (f *botFlow) user(accountID uint64, token string, assetIDs ...string) users.User {
+	steamID := strconv.FormatUint(76561197960265728+accountID, 10)
+	user := users.User{
+		SteamID:  steamID,
+		Username: "user" + steamID,
+		TradeURL: "https://steamcommunity.com/tradeoffer/new/?partner=" + strconv.FormatUint(accountID, 10) + "&token=" + token,
+	}
+	if err := f.db.Create(&user).Error; err != nil {
+		f.t.Fatal(err)
+	}
+	f.econ.AddAccount(steamID, token, assetIDs...)
+	return user
+}
+
+func (f *botFlow) buy() *Order {
+	order, err := Buy(f.db, strconv.FormatUint(uint64(f.listing.ID), 10), f.buyer)
+	if err != nil {
+		f.t.Fatalf("Buy: %v", err)
+	}
+	if order.Delivery != DeliveryBot {
+		f.t.Fatalf("delivery = %s, want %s", order.Delivery, DeliveryBot)
+	}
+	return order
+}
+
+func (f *botFlow) poll() {
+	f.manager.Poll(context.Background())
+}
+
+// offer возвращает последнее предложение бота указанного назначения по заказу
+func (f *botFlow) offer(orderID uint, kind string) tradebot.TradeOffer {
+	var offer tradebot.TradeOffer
+	err := f.db.Where("order_id = ? AND kind = ?", orderID, kind).Order("id DESC").First(&offer).Error
+	if err != nil {
+		f.t.Fatalf("предложение %s по заказу %d: %v", kind, orderID, err)
+	}
+	return offer
+}
+
+func (f *botFlow) hasOffer(orderID uint, kind string) bool {
+	var count int64
+	f.db.Model(&tradebot.TradeOffer{}).Where("order_id = ? AND kind = ?", orderID, kind).Count(&count)
+	return count > 0
+}
+
+func (f *botFlow) expectOrder(orderID uint, status string) Order {
+	var order Order
+	if err := f.db.First(&order, orderID).Error; err != nil {
+		f.t.Fatal(err)
+	}
+	if order.Status != status {
+		f.t.Fatalf("статус заказа %s, want %s", order.Status, status)
+	}
+	return order
+}
+
+func (f *botFlow) expectListing(status string) {
+	var listing listings.Listing
+	if err := f.db.First(&listing, f.listing.ID).Error; err != nil {
+		f.t.Fatal(err)
+	}
+	if listing.Status != status {
+		f.t.Fatalf("статус лота %s, want %s", listing.Status, status)
+	}
+}
+
+func (f *botFlow) expectBalance(user users.User, rubles float64) {
+	account, err := wallet.UserAccount(f.db, user.ID)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if account.Balance != wallet.FromRubles(rubles) {
+		f.t.Fatalf("баланс пользователя %d = %d коп., want %d", user.ID, account.Balance, wallet.FromRubles(rubles))
+	}
+}
+
+func (f *botFlow) expectInventory(user users.User, count int) []string {
+	inv := f.econ.Inventory(user.SteamID)
+	if len(inv) != count {
+		f.t.Fatalf("предметов у %s: %v, want %d", user.SteamID, inv, count)
+	}
+	return inv
+}
+
+// acceptDeposit проводит заказ до отправки предмета покупателю
+func (f *botFlow) acceptDeposit(order *Order) tradebot.TradeOffer {
+	deposit := f.offer(order.ID, tradebot.KindDeposit)
+	if err := f.econ.Accept(deposit.OfferID); err != nil {
+		f.t.Fatalf("Accept deposit: %v", err)
+	}
+	f.poll()
+
+	f.expectOrder(order.ID, StatusTradeSent)
+	f.expectInventory(f.seller, 0)
+	delivery := f.offer(order.ID, tradebot.KindDelivery)
+	if offer, _ := f.econ.Offer(delivery.OfferID); offer.State != tradebot.OfferStateActive {
+		f.t.Fatalf("предложение доставки в состоянии %d, want подтверждённое", offer.State)
+	}
+	return delivery
+}
+
+func TestBotDeliveryAccepted(t *testing.T) {
+	f := newBotFlow(t, nil)
+	order := f.buy()
+	f.expectBalance(f.buyer, 0)
+
+	delivery := f.acceptDeposit(order)
+	if err := f.econ.Accept(delivery.OfferID); err != nil {
+		t.Fatalf("Accept delivery: %v", err)
+	}
+	f.poll()
+
+	f.expectOrder(order.ID, StatusProtected)
+	inv := f.expectInventory(f.buyer, 1)
+	if delivered := f.offer(order.ID, tradebot.KindDelivery); delivered.NewAssetID != inv[0] {
+		t.Errorf("new_asset_id = %s, у покупателя %s", delivered.NewAssetID, inv[0])
+	}
+	f.expectListing(listings.StatusReserved)
+}
+
+func TestBotDeliveryDeclinedReturnsItem(t *testing.T) {
+	f := newBotFlow(t, nil)
+	order := f.buy()
+
+	delivery := f.acceptDeposit(order)
+	if err := f.econ.Decline(delivery.OfferID); err != nil {
+		t.Fatalf("Decline delivery: %v", err)
+	}
+	f.poll()
+
+	f.expectOrder(order.ID, StatusFailed)
+	f.expectBalance(f.buyer, testPrice)
+	ret := f.offer(order.ID, tradebot.KindReturn)
+	if err := f.econ.Accept(ret.OfferID); err != nil {
+		t.Fatalf("Accept return: %v", err)
+	}
+	f.poll()
+
+	f.expectInventory(f.seller, 1)
+	f.expectInventory(f.buyer, 0)
+}
+
+func TestBotDeliveryTransientErrorRetried(t *testing.T) {
+	f := newBotFlow(t, nil)
+	order := f.buy()
+
+	f.econ.FailSend(errors.New("таймаут запроса к Steam"))
+	if err := f.econ.Accept(f.offer(order.ID, tradebot.KindDeposit).OfferID); err != nil {
+		t.Fatalf("Accept deposit: %v", err)
+	}
+	f.poll()
+
+	f.expectOrder(order.ID, StatusTradeSent)
+	f.expectBalance(f.buyer, 0)
+	if f.hasOffer(order.ID, tradebot.KindDelivery) || f.hasOffer(order.ID, tradebot.KindReturn) {
+		t.Fatal("после временной ошибки отправлена доставка или возврат")
+	}
+	if pending := f.offer(order.ID, tradebot.KindDeposit); !pending.HandlePending {
+		t.Fatal("handle_pending не выставлен после временной ошибки")
+	}
+
+	f.poll()
+	f.expectOrder(order.ID, StatusTradeSent)
+	if !f.hasOffer(order.ID, tradebot.KindDelivery) {
+		t.Fatal("доставка не отправлена при повторной обработке")
+	}
+}
+
+func TestBotDeliveryRejectedReturnsItem(t *testing.T) {
+	f := newBotFlow(t, nil)
+	order := f.buy()
+
+	f.econ.FailSend(tradebot.ErrBadTradeToken)
+	if err := f.econ.Accept(f.offer(order.ID, tradebot.KindDeposit).OfferID); err != nil {
+		t.Fatalf("Accept deposit: %v", err)
+	}
+	f.poll()
+
+	f.expectOrder(order.ID, StatusFailed)
+	f.expectBalance(f.buyer, testPrice)
+	if !f.hasOffer(order.ID, tradebot.KindReturn) {
+		t.Fatal("предмет не возвращён продавцу после отказа Steam")
+	}
+}
+
+func TestBotDepositDeclined(t *testing.T) {
+	f := newBotFlow(t, nil)
+	order := f.buy()
+
+	if err := f.econ.Decline(f.offer(order.ID, tradebot.KindDeposit).OfferID); err != nil {
+		t.Fatalf("Decline deposit: %v", err)
+	}
+	f.poll()
+
+	f.expectOrder(order.ID, StatusFailed)
+	f.expectBalance(f.buyer, testPrice)
+	f.expectListing(listings.StatusCancelled)
+	if f.hasOffer(order.ID, tradebot.KindDelivery) {
+		t.Error("после отказа продавца отправлена доставка")
+	}
+}
+
+func TestBotDepositRequestFailed(t *testing.T) {
+	f := newBotFlow(t, nil)
+	// Токен ссылки продавца больше не действует, и Steam не принимает предложение
+	f.econ.AddAccount(f.seller.SteamID, "newToken", testAssetID)
+
+	_, err := Buy(f.db, strconv.FormatUint(uint64(f.listing.ID), 10), f.buyer)
+	if !errors.Is(err, ErrTradeBotFailed) {
+		t.Fatalf("Buy: err = %v, want ErrTradeBotFailed", err)
+	}
+	f.expectBalance(f.buyer, testPrice)
+	f.expectListing(listings.StatusActive)
+}
+
+func TestBotHandlerRetried(t *testing.T) {
+	failures := 1
+	f := newBotFlow(t, func(ctx context.Context, offer tradebot.TradeOffer) error {
+		if failures > 0 {
+			failures--
+			return errors.New("сбой обработчика")
+		}
+		return HandleTradeOffer(ctx, offer)
+	})
+	order := f.buy()
+
+	deposit := f.offer(order.ID, tradebot.KindDeposit)
+	if err := f.econ.Accept(deposit.OfferID); err != nil {
+		t.Fatalf("Accept deposit: %v", err)
+	}
+	f.poll()
+	f.expectOrder(order.ID, StatusPendingTrade)
+	if pending := f.offer(order.ID, tradebot.KindDeposit); !pending.Accepted() || !pending.HandlePending {
+		t.Fatalf("депозит: state = %d, handle_pending = %v", pending.State, pending.HandlePending)
+	}
+
+	f.poll()
+	f.expectOrder(order.ID, StatusTradeSent)
+	if handled := f.offer(order.ID, tradebot.KindDeposit); handled.HandlePending {
+		t.Error("handle_pending не снят после успешной обработки")
+	}
+	if !f.hasOffer(order.ID, tradebot.KindDelivery) {
+		t.Error("доставка не отправлена после повторной обработки")
+	}
+}
+
+func TestBotDeliveryRolledBack(t *testing.T) {
+	f := newBotFlow(t, nil)
+	order := f.buy()
+
+	delivery := f.acceptDeposit(order)
+	if err := f.econ.Accept(delivery.OfferID); err != nil {
+		t.Fatalf("Accept delivery: %v", err)
+	}
+	f.poll()
+	CheckProtectedOrders(f.db)
+	f.expectOrder(order.ID, StatusProtected)
+
+	// Steam откатывает обмен, и предмет возвращается боту под новым assetid
+	if err := f.econ.Rollback(f.offer(order.ID, tradebot.KindDelivery).TradeID); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	CheckProtectedOrders(f.db)
+
+	f.expectOrder(order.ID, StatusDisputed)
+	f.expectBalance(f.buyer, testPrice)
+	f.expectInventory(f.buyer, 0)
+}
+
+func TestBotProtectionCompleted(t *testing.T) {
+	f := newBotFlow(t, nil)
+	order := f.buy()
+
+	delivery := f.acceptDeposit(order)
+	if err := f.econ.Accept(delivery.OfferID); err != nil {
+		t.Fatalf("Accept delivery: %v", err)
+	}
+	f.poll()
+	if err := f.db.Model(&Order{}).Where("id = ?", order.ID).Update("protected_until", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	CheckProtectedOrders(f.db)
+
+	f.expectOrder(order.ID, StatusCompleted)
+	f.expectBalance(f.buyer, 0)
+}
@@ -0,0 +1,179 @@
+package payments
+
+import (
+	"cs-market/internal/storage"
+	"cs-market/internal/users"
+	"cs-market/internal/wallet"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBody - предельный размер тела уведомления платёжной системы
+const maxWebhookBody = 1 << 20
+
+// @Security BearerAuth
+// DepositHandler godoc
+// @Summary Пополнение кошелька
+// @Description Создаёт пополнение и счёт в платёжной системе. Пользователь оплачивает счёт по payment_url, средства зачисляются после уведомления об оплате
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param input body DepositRequest true "Сумма пополнения"
+// @Success 201 {object} PaymentResponse "Пополнение"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка создания пополнения"
+// @Failure 502 {object} response.ErrorResponse "Платёжная система недоступна"
+// @Router /wallet/deposit [post]
+func DepositHandler(c *gin.Context) {
+	var input DepositRequest
+	if err := c.ShouldBindJSON(&input); err != nil || wallet.FromRubles(input.Amount) <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	payment, err := CreateDeposit(storage.DB, user.ID, wallet.FromRubles(input.Amount))
+	if err != nil {
+		if errors.Is(err, ErrProviderFailed) {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Платёжная система недоступна"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания пополнения"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, payment.Response())
+}
+
+// @Security BearerAuth
+// WithdrawHandler godoc
+// @Summary Вывод средств
+// @Description Списывает средства с кошелька и создаёт выплату в платёжной системе. Если платёжная система не ответила, вывод остаётся в обработке до сверки. Средства вернутся на кошелёк, если выплата будет отклонена
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param input body WithdrawRequest true "Сумма и реквизиты"
+// @Success 201 {object} PaymentResponse "Вывод"
+// @Failure 400 {object} response.ErrorResponse "Некорректные данные"
+// @Failure 402 {object} response.ErrorResponse "Недостаточно средств"
+// @Failure 403 {object} response.ErrorResponse "Вывод средств ограничен"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка создания вывода"
+// @Failure 502 {object} response.ErrorResponse "Платёжная система отклонила выплату, средства возвращены"
+// @Router /wallet/withdraw [post]
+func WithdrawHandler(c *gin.Context) {
+	var input WithdrawRequest
+	if err := c.ShouldBindJSON(&input); err != nil || wallet.FromRubles(input.Amount) <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	payment, err := CreateWithdrawal(storage.DB, user.ID, wallet.FromRubles(input.Amount), input.Destination)
+	if err != nil {
+		switch {
+		case errors.Is(err, wallet.ErrInsufficientFunds):
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": "Недостаточно средств"})
+		case errors.Is(err, ErrProviderFailed):
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Платёжная система отклонила выплату, средства возвращены"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания вывода"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, payment.Response())
+}
+
+// @Security BearerAuth
+// GetPaymentsHandler godoc
+// @Summary Пополнения и выводы
+// @Description Получение платежей пользователя, новые первыми
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Success 200 {array} PaymentResponse "Платежи"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения платежей"
+// @Router /profile/wallet/payments [get]
+func GetPaymentsHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var payments []Payment
+	if err := storage.DB.Where("user_id = ?", user.ID).Order("id DESC").Limit(100).Find(&payments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения платежей"})
+		return
+	}
+
+	result := make([]PaymentResponse, 0, len(payments))
+	for i := range payments {
+		result = append(result, payments[i].Response())
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// WebhookHandler godoc
+// @Summary Уведомление платёжной системы
+// @Description Принимает подписанное уведомление об изменении статуса платежа. Повторные уведомления не меняют баланс
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param provider path string true "Платёжная система"
+// @Success 200 {object} response.SuccessResponse "Уведомление обработано"
+// @Failure 400 {object} response.ErrorResponse "Некорректное уведомление"
+// @Failure 401 {object} response.ErrorResponse "Неверная подпись"
+// @Failure 404 {object} response.ErrorResponse "Платёж не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка обработки уведомления"
+// @Router /webhooks/payments/{provider} [post]
+func WebhookHandler(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное уведомление"})
+		return
+	}
+
+	_, err = HandleWebhook(storage.DB, c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Неизвестная платёжная система"})
+		case errors.Is(err, ErrInvalidSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверная подпись"})
+		case errors.Is(err, ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Платёж не найден"})
+		case errors.Is(err, ErrInvalidWebhook), errors.Is(err, ErrUnknownStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное уведомление"})
+		default:
+			log.Printf("Ошибка обработки уведомления %s: %v", c.Param("provider"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки уведомления"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Уведомление обработано"})
+}
+
+func currentUser(c *gin.Context) (users.User, bool) {
+	var user users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return user, false
+	}
+	return user, true
+}
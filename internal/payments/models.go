@@ -0,0 +1,81 @@
+package payments
+
+import (
+	"cs-market/internal/wallet"
+	"strconv"
+	"time"
+)
+
+// Статусы платежа
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Виды платежей
+const (
+	KindDeposit    = "deposit"
+	KindWithdrawal = "withdrawal"
+)
+
+// Payment - пополнение или вывод через платёжную систему. Сумма хранится в копейках
+type Payment struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"not null;index"`
+	Kind        string `gorm:"not null"`
+	Provider    string `gorm:"not null;index:idx_payment_external"`
+	ExternalID  string `gorm:"index:idx_payment_external"`
+	Amount      int64  `gorm:"not null"`
+	Status      string `gorm:"not null;index"`
+	Destination string
+	PaymentURL  string
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Reference - идентификатор платежа в журнале кошелька
+func (p *Payment) Reference() string {
+	return "payment:" + strconv.FormatUint(uint64(p.ID), 10)
+}
+
+func (p *Payment) Response() PaymentResponse {
+	return PaymentResponse{
+		ID:          p.ID,
+		Kind:        p.Kind,
+		Provider:    p.Provider,
+		Amount:      wallet.ToRubles(p.Amount),
+		Currency:    wallet.Currency,
+		Status:      p.Status,
+		PaymentURL:  p.PaymentURL,
+		Destination: p.Destination,
+		CreatedAt:   p.CreatedAt,
+		CompletedAt: p.CompletedAt,
+	}
+}
+
+type DepositRequest struct {
+	// Amount - сумма пополнения в рублях
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+}
+
+type WithdrawRequest struct {
+	// Amount - сумма вывода в рублях
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	// Destination - реквизиты получателя
+	Destination string `json:"destination" binding:"required"`
+}
+
+type PaymentResponse struct {
+	ID          uint       `json:"id"`
+	Kind        string     `json:"kind"`
+	Provider    string     `json:"provider"`
+	Amount      float64    `json:"amount"`
+	Currency    string     `json:"currency"`
+	Status      string     `json:"status"`
+	PaymentURL  string     `json:"payment_url,omitempty"`
+	Destination string     `json:"destination,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
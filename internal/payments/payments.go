@@ -0,0 +1,351 @@
+package payments
+
+import (
+	"context"
+	"cs-market/internal/wallet"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrProviderFailed = errors.New("платёжная система не приняла запрос")
+	ErrUnknownStatus  = errors.New("неизвестный статус платежа")
+)
+
+const (
+	providerTimeout          = 30 * time.Second
+	defaultReconcileInterval = 5 * time.Minute
+	defaultReconcileAfter    = 10 * time.Minute
+	defaultReconcileWindow   = 24 * time.Hour
+)
+
+// ReconcileInterval - период сверки платежей с платёжной системой (PAYMENT_RECONCILE_INTERVAL)
+func ReconcileInterval() time.Duration {
+	return durationEnv("PAYMENT_RECONCILE_INTERVAL", defaultReconcileInterval)
+}
+
+// ReconcileAfter - через сколько после последнего изменения незавершённый платёж сверяется с провайдером (PAYMENT_RECONCILE_AFTER)
+func ReconcileAfter() time.Duration {
+	return durationEnv("PAYMENT_RECONCILE_AFTER", defaultReconcileAfter)
+}
+
+// ReconcileWindow - за какой период завершённые платежи сверяются с журналом и провайдером (PAYMENT_RECONCILE_WINDOW)
+func ReconcileWindow() time.Duration {
+	return durationEnv("PAYMENT_RECONCILE_WINDOW", defaultReconcileWindow)
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// CreateDeposit создаёт пополнение и выставляет счёт у платёжной системы по умолчанию.
+// Средства зачисляются на кошелёк, когда провайдер сообщит об оплате
+func CreateDeposit(db *gorm.DB, userID uint, amount int64) (*Payment, error) {
+	provider, err := Default()
+	if err != nil {
+		return nil, err
+	}
+
+	payment := Payment{UserID: userID, Kind: KindDeposit, Provider: provider.Name(), Amount: amount, Status: StatusPending}
+	if err := db.Create(&payment).Error; err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+	invoice, err := provider.CreateInvoice(ctx, InvoiceRequest{
+		PaymentID:   payment.Reference(),
+		Amount:      amount,
+		Currency:    wallet.Currency,
+		Description: "Пополнение баланса",
+	})
+	if err != nil {
+		return nil, providerFailed(db, &payment, err)
+	}
+
+	err = db.Model(&payment).Updates(map[string]interface{}{
+		"external_id": invoice.ExternalID,
+		"payment_url": invoice.PaymentURL,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return settleIfFinal(db, &payment, invoice.Status)
+}
+
+// CreateWithdrawal списывает средства с кошелька и отправляет выплату через платёжную систему по умолчанию.
+// Средства возвращаются на кошелёк, только если провайдер отклонил выплату. Если ответа нет,
+// выплата могла быть создана, поэтому вывод остаётся в обработке, пока сверка не найдёт её по PaymentID
+func CreateWithdrawal(db *gorm.DB, userID uint, amount int64, destination string) (*Payment, error) {
+	provider, err := Default()
+	if err != nil {
+		return nil, err
+	}
+
+	payment := Payment{
+		UserID:      userID,
+		Kind:        KindWithdrawal,
+		Provider:    provider.Name(),
+		Amount:      amount,
+		Status:      StatusPending,
+		Destination: destination,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		return wallet.Withdraw(tx, userID, amount, payment.Reference())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+	payout, err := provider.Payout(ctx, PayoutRequest{
+		PaymentID:   payment.Reference(),
+		Amount:      amount,
+		Currency:    wallet.Currency,
+		Destination: destination,
+	})
+	if errors.Is(err, ErrPayoutRejected) {
+		return nil, providerFailed(db, &payment, err)
+	}
+	if err != nil {
+		log.Printf("Нет ответа платёжной системы %s по выплате %d, статус будет получен при сверке: %v", payment.Provider, payment.ID, err)
+		return &payment, nil
+	}
+
+	if err := db.Model(&payment).Update("external_id", payout.ExternalID).Error; err != nil {
+		return nil, err
+	}
+	return settleIfFinal(db, &payment, payout.Status)
+}
+
+// providerFailed закрывает платёж, который провайдер не принял
+func providerFailed(db *gorm.DB, payment *Payment, cause error) error {
+	log.Printf("Ошибка платёжной системы %s по платежу %d: %v", payment.Provider, payment.ID, cause)
+	if _, err := ApplyStatus(db, payment.ID, StatusFailed); err != nil {
+		log.Printf("Ошибка закрытия платежа %d: %v", payment.ID, err)
+	}
+	return ErrProviderFailed
+}
+
+func settleIfFinal(db *gorm.DB, payment *Payment, status string) (*Payment, error) {
+	if status == StatusPending {
+		return payment, db.First(payment, payment.ID).Error
+	}
+	return ApplyStatus(db, payment.ID, status)
+}
+
+// ApplyStatus переводит незавершённый платёж в итоговый статус и проводит его по журналу кошелька:
+// успешное пополнение зачисляется, неудавшийся вывод возвращается пользователю.
+// Повторный вызов для завершённого платежа ничего не меняет, поэтому повторные уведомления безопасны
+func ApplyStatus(db *gorm.DB, paymentID uint, status string) (*Payment, error) {
+	if status != StatusPending && status != StatusSucceeded && status != StatusFailed {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStatus, status)
+	}
+
+	var payment Payment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, paymentID).Error
+		if err != nil {
+			return err
+		}
+		if payment.Status != StatusPending || status == StatusPending {
+			return nil
+		}
+
+		if err := postPayment(tx, &payment, status); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		payment.Status = status
+		payment.CompletedAt = &now
+		return tx.Model(&payment).Updates(map[string]interface{}{"status": status, "completed_at": &now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ledgerKind - вид операции в журнале, которой должен быть проведён платёж с итоговым статусом.
+// Пустая строка - движения средств не требуется
+func ledgerKind(payment *Payment, status string) string {
+	switch {
+	case payment.Kind == KindDeposit && status == StatusSucceeded:
+		return wallet.KindDeposit
+	case payment.Kind == KindWithdrawal && status == StatusFailed:
+		return wallet.KindRefund
+	}
+	return ""
+}
+
+// postPayment проводит платёж по журналу, если такой операции ещё нет
+func postPayment(tx *gorm.DB, payment *Payment, status string) error {
+	kind := ledgerKind(payment, status)
+	if kind == "" {
+		return nil
+	}
+	posted, err := wallet.Posted(tx, kind, payment.Reference())
+	if err != nil || posted {
+		return err
+	}
+
+	if kind == wallet.KindDeposit {
+		return wallet.Deposit(tx, payment.UserID, payment.Amount, payment.Reference())
+	}
+	return wallet.CancelWithdrawal(tx, payment.UserID, payment.Amount, payment.Reference())
+}
+
+// HandleWebhook проверяет уведомление провайдера и применяет новый статус платежа
+func HandleWebhook(db *gorm.DB, providerName string, header http.Header, body []byte) (*Payment, error) {
+	provider, err := Provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	event, err := provider.VerifyWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment Payment
+	err = db.Where("provider = ? AND external_id = ?", providerName, event.ExternalID).First(&payment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	return ApplyStatus(db, payment.ID, event.Status)
+}
+
+// Reconcile сверяет платежи с платёжными системами и журналом кошелька:
+// незавершённые платежи, по которым давно нет уведомлений, получают статус у провайдера,
+// а у недавно завершённых проверяется, что операция в журнале есть и провайдер не изменил статус
+func Reconcile(db *gorm.DB) {
+	var pending []Payment
+	err := db.Where("status = ? AND updated_at < ?", StatusPending, time.Now().Add(-ReconcileAfter())).
+		Find(&pending).Error
+	if err != nil {
+		log.Println("Ошибка получения незавершённых платежей:", err)
+		return
+	}
+	for i := range pending {
+		reconcilePending(db, &pending[i])
+	}
+
+	var completed []Payment
+	err = db.Where("status <> ? AND completed_at > ?", StatusPending, time.Now().Add(-ReconcileWindow())).
+		Find(&completed).Error
+	if err != nil {
+		log.Println("Ошибка получения завершённых платежей:", err)
+		return
+	}
+	for i := range completed {
+		reconcileCompleted(db, &completed[i])
+	}
+}
+
+func reconcilePending(db *gorm.DB, payment *Payment) {
+	var (
+		status string
+		err    error
+	)
+	switch {
+	case payment.ExternalID != "":
+		status, err = providerStatus(payment)
+	case payment.Kind == KindWithdrawal:
+		status, err = findPayout(db, payment)
+	default:
+		// Счёт не выставлен, и оплатить пополнение нельзя
+		status = StatusFailed
+	}
+	if err != nil {
+		log.Printf("Ошибка получения статуса платежа %d: %v", payment.ID, err)
+		return
+	}
+
+	if _, err := ApplyStatus(db, payment.ID, status); err != nil {
+		log.Printf("Ошибка сверки платежа %d: %v", payment.ID, err)
+	}
+}
+
+// findPayout ищет у провайдера выплату, ответ на создание которой не получен, и сохраняет её внешний идентификатор.
+// Только если провайдер выплату не получал, вывод считается неудавшимся и средства возвращаются
+func findPayout(db *gorm.DB, payment *Payment) (string, error) {
+	provider, err := Provider(payment.Provider)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+	payout, err := provider.FindPayout(ctx, payment.Reference())
+	if errors.Is(err, ErrPaymentNotFound) {
+		return StatusFailed, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := db.Model(payment).Update("external_id", payout.ExternalID).Error; err != nil {
+		return "", err
+	}
+	return payout.Status, nil
+}
+
+func reconcileCompleted(db *gorm.DB, payment *Payment) {
+	if kind := ledgerKind(payment, payment.Status); kind != "" {
+		posted, err := wallet.Posted(db, kind, payment.Reference())
+		if err != nil {
+			log.Printf("Ошибка сверки журнала по платежу %d: %v", payment.ID, err)
+			return
+		}
+		if !posted {
+			log.Printf("Расхождение журнала: платёж %d в статусе %s не проведён по кошельку", payment.ID, payment.Status)
+		}
+	}
+
+	if payment.ExternalID == "" {
+		return
+	}
+	status, err := providerStatus(payment)
+	if err != nil {
+		log.Printf("Ошибка получения статуса платежа %d: %v", payment.ID, err)
+		return
+	}
+	if status != payment.Status {
+		log.Printf("Расхождение статуса платежа %d: у площадки %s, у %s %s", payment.ID, payment.Status, payment.Provider, status)
+	}
+}
+
+func providerStatus(payment *Payment) (string, error) {
+	provider, err := Provider(payment.Provider)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+	return provider.Status(ctx, payment.ExternalID)
+}
+
+func StartReconciler(db *gorm.DB) {
+	go func() {
+		for {
+			Reconcile(db)
+			time.Sleep(ReconcileInterval())
+		}
+	}()
+}
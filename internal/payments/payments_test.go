@@ -0,0 +1,179 @@
+package payments
+
+import (
+	"context"
+	"cs-market/internal/users"
+	"cs-market/internal/wallet"
+	"errors"
+	"os"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const testAmount = 10000
+
+// flakyProvider - песочница, которая теряет ответ на создание выплаты или отклоняет её
+type flakyProvider struct {
+	*Sandbox
+	// payoutErr возвращается из Payout; lost - выплата при этом всё же создаётся
+	payoutErr error
+	lost      bool
+	findErr   error
+}
+
+func (p *flakyProvider) Name() string {
+	return "flaky"
+}
+
+func (p *flakyProvider) Payout(ctx context.Context, req PayoutRequest) (*Payout, error) {
+	if p.payoutErr == nil || p.lost {
+		payout, _ := p.Sandbox.Payout(ctx, req)
+		if p.payoutErr == nil {
+			return payout, nil
+		}
+	}
+	return nil, p.payoutErr
+}
+
+func (p *flakyProvider) FindPayout(ctx context.Context, paymentID string) (*Payout, error) {
+	if p.findErr != nil {
+		return nil, p.findErr
+	}
+	return p.Sandbox.FindPayout(ctx, paymentID)
+}
+
+// testDB подключается к отдельной базе TEST_DB_DSN и очищает таблицы платежей. Без неё тест пропускается
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN не задан")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&users.User{}, &wallet.Account{}, &wallet.Transaction{}, &wallet.Entry{}, &Payment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec("TRUNCATE payments, entries, transactions, accounts, users RESTART IDENTITY CASCADE").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// withdrawal создаёт пользователя с балансом и выводит его весь через flakyProvider
+func withdrawal(t *testing.T, provider *flakyProvider) (*gorm.DB, users.User, *Payment, error) {
+	db := testDB(t)
+	t.Setenv("PAYMENT_RECONCILE_AFTER", "1ns")
+	Register(provider)
+	SetDefault(provider.Name())
+
+	user := users.User{SteamID: "76561197960266731"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return wallet.Deposit(tx, user.ID, testAmount, "test:deposit")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment, err := CreateWithdrawal(db, user.ID, testAmount, "4111111111111111")
+	return db, user, payment, err
+}
+
+func expectPayment(t *testing.T, db *gorm.DB, id uint, status string) Payment {
+	t.Helper()
+	var payment Payment
+	if err := db.First(&payment, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != status {
+		t.Fatalf("статус платежа %s, want %s", payment.Status, status)
+	}
+	return payment
+}
+
+func expectBalance(t *testing.T, db *gorm.DB, user users.User, balance int64) {
+	t.Helper()
+	account, err := wallet.UserAccount(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != balance {
+		t.Fatalf("баланс %d коп., want %d", account.Balance, balance)
+	}
+}
+
+func TestWithdrawalTimeoutFoundByReconcile(t *testing.T) {
+	provider := &flakyProvider{Sandbox: NewSandbox(SandboxConfig{}), payoutErr: context.DeadlineExceeded, lost: true}
+	db, user, payment, err := withdrawal(t, provider)
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	expectPayment(t, db, payment.ID, StatusPending)
+	expectBalance(t, db, user, 0)
+
+	// Провайдер временно не отвечает и на поиск - вывод остаётся в обработке
+	provider.findErr = errors.New("сбой соединения")
+	Reconcile(db)
+	expectPayment(t, db, payment.ID, StatusPending)
+
+	provider.findErr = nil
+	Reconcile(db)
+	found := expectPayment(t, db, payment.ID, StatusPending)
+	if found.ExternalID == "" {
+		t.Fatal("внешний идентификатор найденной выплаты не сохранён")
+	}
+
+	if err := provider.Complete(found.ExternalID, StatusSucceeded); err != nil {
+		t.Fatal(err)
+	}
+	Reconcile(db)
+	expectPayment(t, db, payment.ID, StatusSucceeded)
+	expectBalance(t, db, user, 0)
+}
+
+func TestWithdrawalTimeoutNotReceived(t *testing.T) {
+	provider := &flakyProvider{Sandbox: NewSandbox(SandboxConfig{}), payoutErr: context.DeadlineExceeded}
+	db, user, payment, err := withdrawal(t, provider)
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	expectBalance(t, db, user, 0)
+
+	Reconcile(db)
+	expectPayment(t, db, payment.ID, StatusFailed)
+	expectBalance(t, db, user, testAmount)
+}
+
+func TestWithdrawalRejected(t *testing.T) {
+	provider := &flakyProvider{Sandbox: NewSandbox(SandboxConfig{}), payoutErr: ErrPayoutRejected}
+	db, user, _, err := withdrawal(t, provider)
+	if !errors.Is(err, ErrProviderFailed) {
+		t.Fatalf("CreateWithdrawal: err = %v, want ErrProviderFailed", err)
+	}
+	expectBalance(t, db, user, testAmount)
+}
+
+func TestInitRequiresProvider(t *testing.T) {
+	t.Setenv("PAYMENT_PROVIDER", "")
+	if err := Init(); err == nil {
+		t.Error("Init без PAYMENT_PROVIDER: ошибки нет")
+	}
+
+	t.Setenv("PAYMENT_PROVIDER", SandboxName)
+	if err := Init(); err != nil {
+		t.Fatalf("Init(sandbox): %v", err)
+	}
+	if p, err := Default(); err != nil || p.Name() != SandboxName {
+		t.Errorf("Default() = %v, %v, want sandbox", p, err)
+	}
+}
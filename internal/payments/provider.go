@@ -0,0 +1,121 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("неизвестная платёжная система")
+	ErrInvalidSignature = errors.New("неверная подпись уведомления")
+	ErrInvalidWebhook   = errors.New("некорректное уведомление")
+	ErrPaymentNotFound  = errors.New("платёж не найден")
+	// ErrPayoutRejected - провайдер отказал в выплате, и она точно не будет проведена
+	ErrPayoutRejected = errors.New("платёжная система отклонила выплату")
+)
+
+// InvoiceRequest - запрос на выставление счёта для пополнения. Суммы в копейках
+type InvoiceRequest struct {
+	// PaymentID - идентификатор платежа на площадке, передаётся провайдеру для сверки
+	PaymentID   string
+	Amount      int64
+	Currency    string
+	Description string
+}
+
+// Invoice - выставленный провайдером счёт
+type Invoice struct {
+	ExternalID string
+	// PaymentURL - страница оплаты, на которую перенаправляется пользователь
+	PaymentURL string
+	Status     string
+}
+
+// PayoutRequest - запрос на выплату пользователю
+type PayoutRequest struct {
+	PaymentID string
+	Amount    int64
+	Currency  string
+	// Destination - реквизиты получателя (номер карты, кошелька и т.п.)
+	Destination string
+}
+
+// Payout - созданная провайдером выплата
+type Payout struct {
+	ExternalID string
+	Status     string
+}
+
+// WebhookEvent - проверенное уведомление провайдера об изменении статуса платежа
+type WebhookEvent struct {
+	ExternalID string
+	Status     string
+}
+
+// PaymentProvider - платёжная система. Статусы, которые возвращают методы, приводятся к Status* пакета
+type PaymentProvider interface {
+	Name() string
+	CreateInvoice(ctx context.Context, req InvoiceRequest) (*Invoice, error)
+	// VerifyWebhook проверяет подпись уведомления и разбирает его.
+	// При неверной подписи возвращает ErrInvalidSignature, при ошибке разбора - ErrInvalidWebhook
+	VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+	Status(ctx context.Context, externalID string) (string, error)
+	// Payout создаёт выплату. Отказ провайдера - ErrPayoutRejected, любая другая ошибка (например, таймаут)
+	// не означает, что выплата не создана
+	Payout(ctx context.Context, req PayoutRequest) (*Payout, error)
+	// FindPayout ищет выплату по PayoutRequest.PaymentID. Если провайдер её не получал - ErrPaymentNotFound
+	FindPayout(ctx context.Context, paymentID string) (*Payout, error)
+}
+
+var (
+	providers       = make(map[string]PaymentProvider)
+	defaultProvider string
+)
+
+// Register добавляет провайдера, уведомления которого принимаются на /webhooks/payments/{name}
+func Register(p PaymentProvider) {
+	providers[p.Name()] = p
+}
+
+// SetDefault задаёт провайдера, через которого создаются новые пополнения и выводы
+func SetDefault(name string) {
+	defaultProvider = name
+}
+
+// Provider возвращает зарегистрированного провайдера по имени
+func Provider(name string) (PaymentProvider, error) {
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Default возвращает провайдера для новых платежей
+func Default() (PaymentProvider, error) {
+	return Provider(defaultProvider)
+}
+
+// Init регистрирует провайдера из PAYMENT_PROVIDER. Переменная обязательна: песочница проводит платежи
+// без настоящих денег, поэтому включается только явным PAYMENT_PROVIDER=sandbox для локальной разработки
+func Init() error {
+	name := strings.TrimSpace(os.Getenv("PAYMENT_PROVIDER"))
+	if name == "" {
+		return errors.New("не задан PAYMENT_PROVIDER, для локальной разработки укажите sandbox")
+	}
+
+	switch name {
+	case SandboxName:
+		log.Println("Платёжная система - песочница, платежи проводятся без настоящих денег")
+		Register(NewSandbox(SandboxConfigFromEnv()))
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	SetDefault(name)
+	return nil
+}
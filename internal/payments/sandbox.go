@@ -0,0 +1,176 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	SandboxName = "sandbox"
+	// SandboxSignatureHeader - заголовок с HMAC-SHA256 тела уведомления в hex
+	SandboxSignatureHeader = "X-Sandbox-Signature"
+)
+
+type SandboxConfig struct {
+	// Secret - ключ подписи уведомлений
+	Secret string
+	// PaymentURL - адрес страницы оплаты, к нему добавляется идентификатор счёта
+	PaymentURL string
+	// AutoComplete - счета и выплаты сразу считаются проведёнными, сверка зачисляет их без уведомлений
+	AutoComplete bool
+}
+
+// SandboxConfigFromEnv читает PAYMENT_SANDBOX_SECRET, PAYMENT_SANDBOX_URL и PAYMENT_SANDBOX_AUTO_COMPLETE.
+// Без секрета он генерируется при запуске, и подписанные уведомления можно получить только через Sandbox.Webhook
+func SandboxConfigFromEnv() SandboxConfig {
+	cfg := SandboxConfig{
+		Secret:       os.Getenv("PAYMENT_SANDBOX_SECRET"),
+		PaymentURL:   os.Getenv("PAYMENT_SANDBOX_URL"),
+		AutoComplete: strings.EqualFold(os.Getenv("PAYMENT_SANDBOX_AUTO_COMPLETE"), "true"),
+	}
+	if cfg.Secret == "" {
+		cfg.Secret = randomID(32)
+		log.Println("PAYMENT_SANDBOX_SECRET не задан, используется случайный ключ")
+	}
+	if cfg.PaymentURL == "" {
+		cfg.PaymentURL = "http://localhost:8080/sandbox/pay/"
+	}
+	return cfg
+}
+
+// Sandbox - платёжная система в памяти процесса для локальной разработки.
+// Оплата счёта и результат выплаты задаются методом Complete, уведомление для него строит Webhook
+type Sandbox struct {
+	cfg SandboxConfig
+
+	mu       sync.Mutex
+	payments map[string]*sandboxPayment
+}
+
+type sandboxPayment struct {
+	PaymentID string
+	Amount    int64
+	Status    string
+}
+
+// sandboxWebhook - тело уведомления песочницы
+type sandboxWebhook struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func NewSandbox(cfg SandboxConfig) *Sandbox {
+	return &Sandbox{cfg: cfg, payments: make(map[string]*sandboxPayment)}
+}
+
+func (s *Sandbox) Name() string {
+	return SandboxName
+}
+
+func (s *Sandbox) create(prefix, paymentID string, amount int64) (string, string) {
+	status := StatusPending
+	if s.cfg.AutoComplete {
+		status = StatusSucceeded
+	}
+
+	id := prefix + randomID(8)
+	s.mu.Lock()
+	s.payments[id] = &sandboxPayment{PaymentID: paymentID, Amount: amount, Status: status}
+	s.mu.Unlock()
+	return id, status
+}
+
+func (s *Sandbox) CreateInvoice(ctx context.Context, req InvoiceRequest) (*Invoice, error) {
+	id, status := s.create("inv_", req.PaymentID, req.Amount)
+	return &Invoice{ExternalID: id, PaymentURL: s.cfg.PaymentURL + id, Status: status}, nil
+}
+
+func (s *Sandbox) Payout(ctx context.Context, req PayoutRequest) (*Payout, error) {
+	id, status := s.create("po_", req.PaymentID, req.Amount)
+	return &Payout{ExternalID: id, Status: status}, nil
+}
+
+func (s *Sandbox) FindPayout(ctx context.Context, paymentID string) (*Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, payment := range s.payments {
+		if strings.HasPrefix(id, "po_") && payment.PaymentID == paymentID {
+			return &Payout{ExternalID: id, Status: payment.Status}, nil
+		}
+	}
+	return nil, ErrPaymentNotFound
+}
+
+func (s *Sandbox) Status(ctx context.Context, externalID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment, ok := s.payments[externalID]
+	if !ok {
+		return "", ErrPaymentNotFound
+	}
+	return payment.Status, nil
+}
+
+func (s *Sandbox) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	signature, err := hex.DecodeString(header.Get(SandboxSignatureHeader))
+	if err != nil || !hmac.Equal(signature, s.sign(body)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event sandboxWebhook
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	return &WebhookEvent{ExternalID: event.ID, Status: event.Status}, nil
+}
+
+// Complete меняет статус счёта или выплаты, как если бы пользователь оплатил счёт или банк провёл выплату
+func (s *Sandbox) Complete(externalID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment, ok := s.payments[externalID]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+	payment.Status = status
+	return nil
+}
+
+// Webhook возвращает подписанное уведомление о текущем статусе платежа
+func (s *Sandbox) Webhook(externalID string) (http.Header, []byte, error) {
+	status, err := s.Status(context.Background(), externalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := json.Marshal(sandboxWebhook{ID: externalID, Status: status})
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(SandboxSignatureHeader, hex.EncodeToString(s.sign(body)))
+	return header, body, nil
+}
+
+func (s *Sandbox) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(s.cfg.Secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,265 @@
+// Package steamapi - клиент Steam Web API и инвентарей Steam Community
+// с ограничением частоты запросов и повторами при 429 и 5xx
+package steamapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAPIURL       = "https://api.steampowered.com"
+	DefaultCommunityURL = "https://steamcommunity.com"
+
+	defaultTimeout    = 10 * time.Second
+	defaultRate       = 1.0
+	defaultBurst      = 5
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	maxBackoff        = 30 * time.Second
+)
+
+// Config - параметры клиента. Нулевые значения заменяются значениями по умолчанию
+type Config struct {
+	APIKey       string
+	APIURL       string
+	CommunityURL string
+	Timeout      time.Duration
+	// Rate - запросов в секунду, Burst - сколько запросов можно сделать подряд без ожидания
+	Rate       float64
+	Burst      int
+	MaxRetries int
+	// Backoff - пауза перед первым повтором, каждая следующая вдвое длиннее
+	Backoff    time.Duration
+	HTTPClient *http.Client
+}
+
+type Client struct {
+	key          string
+	apiURL       string
+	communityURL string
+	http         *http.Client
+	limiter      *limiter
+	maxRetries   int
+	backoff      time.Duration
+}
+
+func New(cfg Config) *Client {
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultAPIURL
+	}
+	if cfg.CommunityURL == "" {
+		cfg.CommunityURL = DefaultCommunityURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Rate <= 0 {
+		cfg.Rate = defaultRate
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = defaultBurst
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	return &Client{
+		key:          cfg.APIKey,
+		apiURL:       cfg.APIURL,
+		communityURL: cfg.CommunityURL,
+		http:         cfg.HTTPClient,
+		limiter:      newLimiter(cfg.Rate, cfg.Burst),
+		maxRetries:   cfg.MaxRetries,
+		backoff:      cfg.Backoff,
+	}
+}
+
+// ConfigFromEnv читает настройки клиента из окружения: STEAM_API_KEY, STEAM_API_URL,
+// STEAM_COMMUNITY_URL, STEAM_API_TIMEOUT, STEAM_API_RATE, STEAM_API_BURST, STEAM_API_RETRIES
+func ConfigFromEnv() Config {
+	cfg := Config{
+		APIKey:       os.Getenv("STEAM_API_KEY"),
+		APIURL:       os.Getenv("STEAM_API_URL"),
+		CommunityURL: os.Getenv("STEAM_COMMUNITY_URL"),
+		MaxRetries:   defaultMaxRetries,
+	}
+	if d, err := time.ParseDuration(os.Getenv("STEAM_API_TIMEOUT")); err == nil {
+		cfg.Timeout = d
+	}
+	if r, err := strconv.ParseFloat(os.Getenv("STEAM_API_RATE"), 64); err == nil {
+		cfg.Rate = r
+	}
+	if b, err := strconv.Atoi(os.Getenv("STEAM_API_BURST")); err == nil {
+		cfg.Burst = b
+	}
+	if n, err := strconv.Atoi(os.Getenv("STEAM_API_RETRIES")); err == nil {
+		cfg.MaxRetries = n
+	}
+	return cfg
+}
+
+var (
+	defaultClient *Client
+	defaultMu     sync.Mutex
+)
+
+// Default возвращает общий клиент, настроенный из окружения при первом обращении
+func Default() *Client {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultClient == nil {
+		defaultClient = New(ConfigFromEnv())
+	}
+	return defaultClient
+}
+
+// SetDefault подменяет общий клиент, например клиентом фейкового сервера steamapitest
+func SetDefault(c *Client) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = c
+}
+
+// getJSON выполняет GET с ограничением частоты и повторами и декодирует ответ в v.
+// Ключ API добавляется к запросу здесь и не попадает в ошибки
+func (c *Client) getJSON(ctx context.Context, method, base, path string, query url.Values, v interface{}) error {
+	return c.getJSONWithKey(ctx, c.key, method, base, path, query, v)
+}
+
+// getJSONWithKey - getJSON с ключом пользователя вместо ключа площадки, для методов,
+// которые отдают данные только владельцу ключа
+func (c *Client) getJSONWithKey(ctx context.Context, key, method, base, path string, query url.Values, v interface{}) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	// Ключ нужен только Web API, инвентари Steam Community запрашиваются без него
+	withKey := query
+	if method != methodInventory {
+		if key == "" {
+			return ErrNoAPIKey
+		}
+		withKey = url.Values{}
+		for k, vs := range query {
+			withKey[k] = vs
+		}
+		withKey.Set("key", key)
+	}
+	target := base + path + "?" + withKey.Encode()
+
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.retryDelay(attempt, lastErr)); err != nil {
+				return err
+			}
+		}
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		body, err := c.do(ctx, method, target)
+		if err == nil {
+			if err := json.Unmarshal(body, v); err != nil {
+				return &APIError{Method: method, Err: fmt.Errorf("ошибка разбора ответа: %w", err)}
+			}
+			return nil
+		}
+		lastErr = err
+		if !retryable(err) {
+			return err
+		}
+	}
+	return lastErr
+}
+
+func (c *Client) do(ctx context.Context, method, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, &APIError{Method: method, Err: redact(err)}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &APIError{Method: method, Err: redact(err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, &APIError{
+			Method:     method,
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &APIError{Method: method, Err: redact(err)}
+	}
+	return body, nil
+}
+
+// retryDelay - экспоненциальная пауза с разбросом, Retry-After от Steam имеет приоритет
+func (c *Client) retryDelay(attempt int, lastErr error) time.Duration {
+	var apiErr *APIError
+	if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	d := c.backoff << (attempt - 1)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// redact убирает из ошибки net/http адрес запроса, в котором содержится ключ API
+func redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
@@ -0,0 +1,344 @@
+package steamapi_test
+
+import (
+	"context"
+	"cs-market/internal/steamapi"
+	"cs-market/internal/steamapi/steamapitest"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testSteamID = "76561198000000001"
+	testKey     = "SECRETAPIKEY"
+)
+
+func newServer(t *testing.T) *steamapitest.Server {
+	srv := steamapitest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddPlayer(testSteamID)
+	return srv
+}
+
+func TestRetriesTransientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable} {
+		srv := newServer(t)
+		srv.Fail(status, status)
+
+		bans, err := srv.Client().PlayerBans(context.Background(), testSteamID)
+		if err != nil {
+			t.Fatalf("статус %d: %v", status, err)
+		}
+		if bans.SteamID != testSteamID {
+			t.Errorf("статус %d: SteamId = %s", status, bans.SteamID)
+		}
+		if n := srv.Requests(); n != 3 {
+			t.Errorf("статус %d: запросов %d, want 3", status, n)
+		}
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusTooManyRequests, steamapi.ErrRateLimited},
+		{http.StatusServiceUnavailable, steamapi.ErrUnavailable},
+	}
+	for _, tt := range tests {
+		srv := newServer(t)
+		srv.Fail(tt.status, tt.status, tt.status, tt.status)
+
+		_, err := srv.Client().PlayerBans(context.Background(), testSteamID)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("статус %d: err = %v, want %v", tt.status, err, tt.want)
+		}
+		// Первая попытка и три повтора
+		if n := srv.Requests(); n != 4 {
+			t.Errorf("статус %d: запросов %d, want 4", tt.status, n)
+		}
+	}
+}
+
+func TestNoRetryOnClientErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusNotFound, steamapi.ErrNotFound},
+		{http.StatusForbidden, steamapi.ErrNoAPIKey},
+	}
+	for _, tt := range tests {
+		srv := newServer(t)
+		srv.Fail(tt.status)
+
+		_, err := srv.Client().PlayerBans(context.Background(), testSteamID)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("статус %d: err = %v, want %v", tt.status, err, tt.want)
+		}
+		if n := srv.Requests(); n != 1 {
+			t.Errorf("статус %d: запросов %d, want 1", tt.status, n)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts = append(attempts, time.Now())
+		first := len(attempts) == 1
+		mu.Unlock()
+
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"response":{"player_level":7}}`)
+	}))
+	defer srv.Close()
+
+	client := steamapi.New(steamapi.Config{APIKey: testKey, APIURL: srv.URL, Rate: 1000, Burst: 1000, MaxRetries: 3, Backoff: time.Millisecond})
+	level, err := client.SteamLevel(context.Background(), testSteamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != 7 {
+		t.Errorf("уровень %d, want 7", level)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 2 {
+		t.Fatalf("запросов %d, want 2", len(attempts))
+	}
+	if wait := attempts[1].Sub(attempts[0]); wait < time.Second {
+		t.Errorf("повтор через %v, want не раньше Retry-After 1s", wait)
+	}
+}
+
+func TestRetryCancelledByContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := steamapi.New(steamapi.Config{APIKey: testKey, APIURL: srv.URL, Rate: 1000, Burst: 1000, MaxRetries: 3})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.PlayerBans(ctx, testSteamID)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ожидание повтора не прервано контекстом: %v", elapsed)
+	}
+}
+
+func TestKeyRedacted(t *testing.T) {
+	// Закрытый сервер: net/http вернёт ошибку с полным адресом запроса, в котором есть ключ
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	client := steamapi.New(steamapi.Config{APIKey: testKey, APIURL: srv.URL, MaxRetries: 1, Backoff: time.Millisecond})
+	_, err := client.PlayerBans(context.Background(), testSteamID)
+	if err == nil {
+		t.Fatal("ожидалась ошибка соединения")
+	}
+	if strings.Contains(err.Error(), testKey) {
+		t.Errorf("ключ API попал в ошибку: %v", err)
+	}
+	var apiErr *steamapi.APIError
+	if !errors.As(err, &apiErr) || apiErr.Method != "ISteamUser/GetPlayerBans" {
+		t.Errorf("err = %#v, want APIError метода ISteamUser/GetPlayerBans", err)
+	}
+}
+
+func TestNoAPIKey(t *testing.T) {
+	srv := newServer(t)
+	client := steamapi.New(steamapi.Config{APIURL: srv.URL, CommunityURL: srv.URL})
+
+	if _, err := client.PlayerBans(context.Background(), testSteamID); !errors.Is(err, steamapi.ErrNoAPIKey) {
+		t.Errorf("err = %v, want ErrNoAPIKey", err)
+	}
+	if n := srv.Requests(); n != 0 {
+		t.Errorf("запросов без ключа %d, want 0", n)
+	}
+	// Инвентари Steam Community ключа не требуют
+	if _, err := client.Inventory(context.Background(), testSteamID, steamapi.AppIDCS2, steamapi.ContextIDCS2, 10, ""); err != nil {
+		t.Errorf("Inventory без ключа: %v", err)
+	}
+}
+
+func TestInventoryPaging(t *testing.T) {
+	srv := newServer(t)
+	var want []string
+	srv.UpdatePlayer(testSteamID, func(p *steamapitest.Player) {
+		for i := 1; i <= 5; i++ {
+			id := fmt.Sprintf("%d", 1000+i)
+			want = append(want, id)
+			p.Assets = append(p.Assets, json.RawMessage(fmt.Sprintf(`{"assetid":%q,"classid":"1","instanceid":"0","amount":"1"}`, id)))
+		}
+	})
+	client := srv.Client()
+
+	var got []string
+	cursor := ""
+	for pages := 1; ; pages++ {
+		page, err := client.Inventory(context.Background(), testSteamID, steamapi.AppIDCS2, steamapi.ContextIDCS2, 2, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.TotalInventoryCount != len(want) {
+			t.Errorf("total_inventory_count = %d, want %d", page.TotalInventoryCount, len(want))
+		}
+		for _, raw := range page.Assets {
+			var asset struct {
+				AssetID string `json:"assetid"`
+			}
+			if err := json.Unmarshal(raw, &asset); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, asset.AssetID)
+		}
+		if page.MoreItems == 0 {
+			if pages != 3 {
+				t.Errorf("страниц %d, want 3", pages)
+			}
+			break
+		}
+		if page.LastAssetID == "" || pages > len(want) {
+			t.Fatalf("страница %d: more_items без last_assetid", pages)
+		}
+		cursor = page.LastAssetID
+	}
+
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("предметы %v, want %v", got, want)
+	}
+}
+
+func TestInventoryPrivate(t *testing.T) {
+	srv := newServer(t)
+	client := srv.Client()
+	ctx := context.Background()
+
+	private, err := client.InventoryPrivate(ctx, testSteamID, steamapi.AppIDCS2, steamapi.ContextIDCS2)
+	if err != nil || private {
+		t.Fatalf("открытый инвентарь: private = %v, err = %v", private, err)
+	}
+
+	srv.UpdatePlayer(testSteamID, func(p *steamapitest.Player) { p.PrivateInventory = true })
+	if _, err := client.Inventory(ctx, testSteamID, steamapi.AppIDCS2, steamapi.ContextIDCS2, 10, ""); !errors.Is(err, steamapi.ErrPrivateInventory) {
+		t.Errorf("Inventory: err = %v, want ErrPrivateInventory", err)
+	}
+	private, err = client.InventoryPrivate(ctx, testSteamID, steamapi.AppIDCS2, steamapi.ContextIDCS2)
+	if err != nil || !private {
+		t.Errorf("закрытый инвентарь: private = %v, err = %v", private, err)
+	}
+
+	// Сбой Steam не считается закрытым инвентарём
+	srv.Fail(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	srv.UpdatePlayer(testSteamID, func(p *steamapitest.Player) { p.PrivateInventory = false })
+	if _, err := client.InventoryPrivate(ctx, testSteamID, steamapi.AppIDCS2, steamapi.ContextIDCS2); !errors.Is(err, steamapi.ErrUnavailable) {
+		t.Errorf("InventoryPrivate при 503: err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestTradeHistory(t *testing.T) {
+	const userKey = "0123456789ABCDEF0123456789ABCDEF"
+	srv := newServer(t)
+	srv.AddTrades(userKey,
+		steamapi.Trade{TradeID: "1", SteamIDOther: testSteamID, Status: steamapi.TradeStatusComplete},
+		steamapi.Trade{TradeID: "2", SteamIDOther: testSteamID, Status: steamapi.TradeStatusComplete,
+			AssetsGiven: []steamapi.TradeAsset{{AppID: steamapi.AppIDCS2, ContextID: "2", AssetID: "111", NewAssetID: "999"}}},
+	)
+	client := srv.Client()
+	ctx := context.Background()
+
+	trades, err := client.TradeHistory(ctx, userKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].TradeID != "2" {
+		t.Fatalf("обмены %+v, want только последний", trades)
+	}
+	if given := trades[0].AssetsGiven; len(given) != 1 || given[0].NewAssetID != "999" {
+		t.Errorf("assets_given %+v, want new_assetid 999", given)
+	}
+
+	// История запрашивается ключом пользователя, а не ключом площадки
+	if _, err := client.TradeHistory(ctx, "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", 1); !errors.Is(err, steamapi.ErrInvalidAPIKey) {
+		t.Errorf("чужой ключ: err = %v, want ErrInvalidAPIKey", err)
+	}
+	if _, err := client.TradeHistory(ctx, "", 1); !errors.Is(err, steamapi.ErrInvalidAPIKey) {
+		t.Errorf("без ключа: err = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestTradeHistorySince(t *testing.T) {
+	const userKey = "0123456789ABCDEF0123456789ABCDEF"
+	srv := newServer(t)
+	since := time.Now().Add(-time.Hour)
+	srv.AddTrades(userKey,
+		steamapi.Trade{TradeID: "1", TimeInit: since.Add(-time.Minute).Unix()},
+		steamapi.Trade{TradeID: "2", TimeInit: since.Unix()},
+		steamapi.Trade{TradeID: "3", TimeInit: since.Add(time.Minute).Unix()},
+		steamapi.Trade{TradeID: "4", TimeInit: since.Add(2 * time.Minute).Unix()},
+		steamapi.Trade{TradeID: "5", TimeInit: since.Add(3 * time.Minute).Unix()},
+	)
+
+	trades, err := srv.Client().TradeHistorySince(context.Background(), userKey, since, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, trade := range trades {
+		ids = append(ids, trade.TradeID)
+	}
+	if strings.Join(ids, ",") != "5,4,3,2" {
+		t.Errorf("обмены %v, want 5,4,3,2", ids)
+	}
+	// Третья страница начинается с обмена раньше since, дальше история не запрашивается
+	if n := srv.Requests(); n != 3 {
+		t.Errorf("запросов %d, want 3", n)
+	}
+}
+
+func TestTradeStatus(t *testing.T) {
+	const userKey = "0123456789ABCDEF0123456789ABCDEF"
+	srv := newServer(t)
+	srv.AddTrades(userKey, steamapi.Trade{TradeID: "7", Status: steamapi.TradeStatusComplete})
+	srv.AddTrades(userKey, steamapi.Trade{TradeID: "7", Status: steamapi.TradeStatusProtectionRollback})
+	client := srv.Client()
+	ctx := context.Background()
+
+	trade, err := client.TradeStatus(ctx, userKey, "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !steamapi.TradeRolledBack(trade.Status) {
+		t.Errorf("status = %d, want откат", trade.Status)
+	}
+	if _, err := client.TradeStatus(ctx, userKey, "8"); !errors.Is(err, steamapi.ErrNotFound) {
+		t.Errorf("неизвестный обмен: err = %v, want ErrNotFound", err)
+	}
+	if _, err := client.TradeStatus(ctx, "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", "7"); !errors.Is(err, steamapi.ErrInvalidAPIKey) {
+		t.Errorf("чужой ключ: err = %v, want ErrInvalidAPIKey", err)
+	}
+}
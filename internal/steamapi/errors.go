@@ -0,0 +1,66 @@
+package steamapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrNoAPIKey         = errors.New("не задан STEAM_API_KEY")
+	ErrInvalidAPIKey    = errors.New("недействительный ключ Steam Web API пользователя")
+	ErrRateLimited      = errors.New("превышен лимит запросов к Steam")
+	ErrUnavailable      = errors.New("Steam недоступен")
+	ErrPrivateInventory = errors.New("инвентарь Steam закрыт")
+	ErrNotFound         = errors.New("не найдено в Steam")
+	ErrPlayerNotFound   = errors.New("игрок Steam не найден")
+)
+
+// APIError - ошибка запроса к Steam. Не содержит адреса запроса, чтобы ключ API не попадал в логи
+type APIError struct {
+	// Method - вызванный метод API, например ISteamUser/GetPlayerBans
+	Method     string
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("steamapi %s: статус %d", e.Method, e.StatusCode)
+	}
+	return fmt.Sprintf("steamapi %s: %v", e.Method, e.Err)
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusForbidden || e.StatusCode == http.StatusUnauthorized:
+		if e.Method == methodInventory {
+			return ErrPrivateInventory
+		}
+		if e.Method == methodTradeHistory || e.Method == methodTradeStatus {
+			return ErrInvalidAPIKey
+		}
+		return ErrNoAPIKey
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	}
+	return e.Err
+}
+
+// retryable - повторяются 429, 5xx и сетевые ошибки
+func retryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == 0 {
+		return apiErr.Err != nil
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+}
@@ -0,0 +1,247 @@
+package steamapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	methodPlayerBans      = "ISteamUser/GetPlayerBans"
+	methodPlayerSummaries = "ISteamUser/GetPlayerSummaries"
+	methodSteamLevel      = "IPlayerService/GetSteamLevel"
+	methodInventory       = "inventory"
+	methodTradeHistory    = "IEconService/GetTradeHistory"
+	methodTradeStatus     = "IEconService/GetTradeStatus"
+)
+
+// CommunityVisibilityPublic - значение communityvisibilitystate открытого профиля
+const CommunityVisibilityPublic = 3
+
+// Инвентарь CS2
+const (
+	AppIDCS2     = 730
+	ContextIDCS2 = 2
+)
+
+// PlayerBans - блокировки аккаунта из ISteamUser/GetPlayerBans
+type PlayerBans struct {
+	SteamID          string `json:"SteamId"`
+	CommunityBanned  bool   `json:"CommunityBanned"`
+	VACBanned        bool   `json:"VACBanned"`
+	NumberOfVACBans  int    `json:"NumberOfVACBans"`
+	DaysSinceLastBan int    `json:"DaysSinceLastBan"`
+	NumberOfGameBans int    `json:"NumberOfGameBans"`
+	EconomyBan       string `json:"EconomyBan"`
+}
+
+// PlayerSummary - профиль из ISteamUser/GetPlayerSummaries
+type PlayerSummary struct {
+	SteamID                  string `json:"steamid"`
+	PersonaName              string `json:"personaname"`
+	AvatarFull               string `json:"avatarfull"`
+	CommunityVisibilityState int    `json:"communityvisibilitystate"`
+	ProfileState             int    `json:"profilestate"`
+	// TimeCreated - время создания аккаунта (unix), у закрытых профилей не передаётся
+	TimeCreated int64 `json:"timecreated"`
+}
+
+// InventoryPage - страница инвентаря Steam Community. Предметы и описания не разбираются,
+// их схема принадлежит пакету inventory
+type InventoryPage struct {
+	Assets              []json.RawMessage `json:"assets"`
+	Descriptions        []json.RawMessage `json:"descriptions"`
+	MoreItems           int               `json:"more_items,omitempty"`
+	LastAssetID         string            `json:"last_assetid,omitempty"`
+	TotalInventoryCount int               `json:"total_inventory_count"`
+}
+
+// Состояния обмена (ETradeStatus)
+const (
+	// TradeStatusComplete - обмен завершён, предметы у новых владельцев
+	TradeStatusComplete                 = 3
+	TradeStatusPartialSupportRollback   = 5
+	TradeStatusFullSupportRollback      = 6
+	TradeStatusSupportRollbackSelective = 7
+	TradeStatusEscrowRollback           = 11
+	// TradeStatusProtectionRollback - обмен отменён в течение срока защиты, предметы вернулись отправителю
+	TradeStatusProtectionRollback = 12
+)
+
+// TradeRolledBack - обмен откачен поддержкой Steam или по защите обмена, и предметы вернулись прежним владельцам
+func TradeRolledBack(status int) bool {
+	switch status {
+	case TradeStatusPartialSupportRollback, TradeStatusFullSupportRollback, TradeStatusSupportRollbackSelective,
+		TradeStatusEscrowRollback, TradeStatusProtectionRollback:
+		return true
+	}
+	return false
+}
+
+// TradeAsset - предмет обмена. NewAssetID - assetid предмета у получателя
+type TradeAsset struct {
+	AppID        int    `json:"appid"`
+	ContextID    string `json:"contextid"`
+	AssetID      string `json:"assetid"`
+	ClassID      string `json:"classid"`
+	InstanceID   string `json:"instanceid"`
+	NewAssetID   string `json:"new_assetid"`
+	NewContextID string `json:"new_contextid"`
+}
+
+// Trade - обмен из IEconService/GetTradeHistory с точки зрения владельца ключа
+type Trade struct {
+	TradeID        string       `json:"tradeid"`
+	SteamIDOther   string       `json:"steamid_other"`
+	TimeInit       int64        `json:"time_init"`
+	Status         int          `json:"status"`
+	AssetsGiven    []TradeAsset `json:"assets_given"`
+	AssetsReceived []TradeAsset `json:"assets_received"`
+}
+
+func (c *Client) PlayerBans(ctx context.Context, steamID string) (PlayerBans, error) {
+	var result struct {
+		Players []PlayerBans `json:"players"`
+	}
+	err := c.getJSON(ctx, methodPlayerBans, c.apiURL, "/ISteamUser/GetPlayerBans/v1/",
+		url.Values{"steamids": {steamID}}, &result)
+	if err != nil {
+		return PlayerBans{}, err
+	}
+	if len(result.Players) != 1 {
+		return PlayerBans{}, ErrPlayerNotFound
+	}
+	return result.Players[0], nil
+}
+
+func (c *Client) PlayerSummary(ctx context.Context, steamID string) (PlayerSummary, error) {
+	var result struct {
+		Response struct {
+			Players []PlayerSummary `json:"players"`
+		} `json:"response"`
+	}
+	err := c.getJSON(ctx, methodPlayerSummaries, c.apiURL, "/ISteamUser/GetPlayerSummaries/v2/",
+		url.Values{"steamids": {steamID}}, &result)
+	if err != nil {
+		return PlayerSummary{}, err
+	}
+	if len(result.Response.Players) != 1 {
+		return PlayerSummary{}, ErrPlayerNotFound
+	}
+	return result.Response.Players[0], nil
+}
+
+func (c *Client) SteamLevel(ctx context.Context, steamID string) (int, error) {
+	var result struct {
+		Response struct {
+			PlayerLevel int `json:"player_level"`
+		} `json:"response"`
+	}
+	err := c.getJSON(ctx, methodSteamLevel, c.apiURL, "/IPlayerService/GetSteamLevel/v1/",
+		url.Values{"steamid": {steamID}}, &result)
+	return result.Response.PlayerLevel, err
+}
+
+// Inventory загружает одну страницу инвентаря. startAssetID - курсор last_assetid предыдущей страницы
+func (c *Client) Inventory(ctx context.Context, steamID string, appID, contextID, count int, startAssetID string) (*InventoryPage, error) {
+	query := url.Values{"l": {"english"}, "count": {strconv.Itoa(count)}}
+	if startAssetID != "" {
+		query.Set("start_assetid", startAssetID)
+	}
+
+	var page InventoryPage
+	path := fmt.Sprintf("/inventory/%s/%d/%d", url.PathEscape(steamID), appID, contextID)
+	if err := c.getJSON(ctx, methodInventory, c.communityURL, path, query, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// InventoryPrivate проверяет, закрыт ли инвентарь, запрашивая один предмет.
+// Открытый профиль (communityvisibilitystate) этого не гарантирует: инвентарь можно скрыть отдельно
+func (c *Client) InventoryPrivate(ctx context.Context, steamID string, appID, contextID int) (bool, error) {
+	_, err := c.Inventory(ctx, steamID, appID, contextID, 1, "")
+	if errors.Is(err, ErrPrivateInventory) {
+		return true, nil
+	}
+	return false, err
+}
+
+// TradeHistory возвращает последние max обменов владельца ключа apiKey, новые первыми.
+// Steam отдаёт историю обменов только по ключу её владельца, поэтому ключ площадки здесь не используется.
+// Пустой или неверный ключ - ErrInvalidAPIKey
+func (c *Client) TradeHistory(ctx context.Context, apiKey string, max int) ([]Trade, error) {
+	if apiKey == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	var result struct {
+		Response struct {
+			Trades []Trade `json:"trades"`
+		} `json:"response"`
+	}
+	query := url.Values{"max_trades": {strconv.Itoa(max)}, "get_descriptions": {"0"}}
+	err := c.getJSONWithKey(ctx, apiKey, methodTradeHistory, c.apiURL, "/IEconService/GetTradeHistory/v1/", query, &result)
+	return result.Response.Trades, err
+}
+
+// TradeStatus возвращает обмен tradeID владельца ключа apiKey с его текущим состоянием.
+// Пустой или неверный ключ - ErrInvalidAPIKey, обмен не найден - ErrNotFound
+func (c *Client) TradeStatus(ctx context.Context, apiKey, tradeID string) (Trade, error) {
+	if apiKey == "" {
+		return Trade{}, ErrInvalidAPIKey
+	}
+	var result struct {
+		Response struct {
+			Trades []Trade `json:"trades"`
+		} `json:"response"`
+	}
+	query := url.Values{"tradeid": {tradeID}, "get_descriptions": {"0"}}
+	err := c.getJSONWithKey(ctx, apiKey, methodTradeStatus, c.apiURL, "/IEconService/GetTradeStatus/v1/", query, &result)
+	if err != nil {
+		return Trade{}, err
+	}
+	if len(result.Response.Trades) == 0 {
+		return Trade{}, ErrNotFound
+	}
+	return result.Response.Trades[0], nil
+}
+
+// TradeHistorySince возвращает обмены владельца ключа apiKey, начатые не раньше since, новые первыми.
+// История запрашивается страницами по pageSize обменов, пока не дойдёт до более ранних обменов,
+// поэтому нужный обмен не теряется за последними pageSize, а лишние страницы не загружаются
+func (c *Client) TradeHistorySince(ctx context.Context, apiKey string, since time.Time, pageSize int) ([]Trade, error) {
+	if apiKey == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	var trades []Trade
+	query := url.Values{"max_trades": {strconv.Itoa(pageSize)}, "get_descriptions": {"0"}}
+	for {
+		var result struct {
+			Response struct {
+				Trades []Trade `json:"trades"`
+				More   bool    `json:"more"`
+			} `json:"response"`
+		}
+		err := c.getJSONWithKey(ctx, apiKey, methodTradeHistory, c.apiURL, "/IEconService/GetTradeHistory/v1/", query, &result)
+		if err != nil {
+			return nil, err
+		}
+		page := result.Response.Trades
+		for _, trade := range page {
+			if trade.TimeInit < since.Unix() {
+				return trades, nil
+			}
+			trades = append(trades, trade)
+		}
+		if !result.Response.More || len(page) == 0 {
+			return trades, nil
+		}
+		last := page[len(page)-1]
+		query.Set("start_after_time", strconv.FormatInt(last.TimeInit, 10))
+		query.Set("start_after_tradeid", last.TradeID)
+	}
+}
@@ -0,0 +1,335 @@
+// Package steamapitest - фейковый сервер Steam Web API и инвентарей на httptest
+// для офлайн-проверки пакетов, работающих со Steam
+package steamapitest
+
+import (
+	"cs-market/internal/steamapi"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Player - данные игрока, которые отдаёт фейковый сервер
+type Player struct {
+	Summary steamapi.PlayerSummary
+	Bans    steamapi.PlayerBans
+	Level   int
+	// Inventory - предметы и описания инвентаря CS2 в формате Steam
+	Assets       []json.RawMessage
+	Descriptions []json.RawMessage
+	// PrivateInventory - инвентарь отвечает 403
+	PrivateInventory bool
+}
+
+type Server struct {
+	*httptest.Server
+
+	// APIKey - ключ, который должен передавать клиент, пустой - любой
+	APIKey string
+	// TimeOffset - на сколько время, отдаваемое QueryTime, опережает локальное
+	TimeOffset time.Duration
+
+	mu      sync.Mutex
+	players map[string]*Player
+	// trades - истории обменов по ключам API пользователей, новые первыми
+	trades map[string][]steamapi.Trade
+	// failures - сколько следующих запросов ответить этим статусом
+	failures []int
+	requests int
+}
+
+func NewServer() *Server {
+	s := &Server{players: make(map[string]*Player), trades: make(map[string][]steamapi.Trade)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ISteamUser/GetPlayerBans/v1/", s.api(s.playerBans))
+	mux.HandleFunc("/ISteamUser/GetPlayerSummaries/v2/", s.api(s.playerSummaries))
+	mux.HandleFunc("/IPlayerService/GetSteamLevel/v1/", s.api(s.steamLevel))
+	mux.HandleFunc("/ITwoFactorService/QueryTime/v0001", s.handle(s.queryTime))
+	mux.HandleFunc("/IEconService/GetTradeHistory/v1/", s.handle(s.tradeHistory))
+	mux.HandleFunc("/IEconService/GetTradeStatus/v1/", s.handle(s.tradeStatus))
+	mux.HandleFunc("/inventory/", s.handle(s.inventory))
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Client возвращает клиент steamapi, направленный на фейковый сервер, без пауз между повторами
+func (s *Server) Client() *steamapi.Client {
+	key := s.APIKey
+	if key == "" {
+		key = "test"
+	}
+	return steamapi.New(steamapi.Config{
+		APIKey:       key,
+		APIURL:       s.URL,
+		CommunityURL: s.URL,
+		Rate:         1000,
+		Burst:        1000,
+		MaxRetries:   3,
+		Backoff:      time.Millisecond,
+	})
+}
+
+// AddPlayer регистрирует игрока с открытым профилем, без блокировок и с пустым инвентарём.
+// Возвращённого игрока можно настроить до первых запросов, дальше - только через UpdatePlayer
+func (s *Server) AddPlayer(steamID string) *Player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := &Player{
+		Summary: steamapi.PlayerSummary{
+			SteamID:                  steamID,
+			PersonaName:              "player" + steamID,
+			CommunityVisibilityState: steamapi.CommunityVisibilityPublic,
+			TimeCreated:              time.Now().AddDate(-5, 0, 0).Unix(),
+		},
+		Bans:  steamapi.PlayerBans{SteamID: steamID, EconomyBan: "none"},
+		Level: 10,
+	}
+	s.players[steamID] = p
+	return p
+}
+
+// AddTrades регистрирует ключ API пользователя и добавляет обмены, переданные в порядке совершения, в начало его истории.
+// Без обменов просто регистрирует ключ, запросы с незарегистрированным ключом получают 403.
+// Повторно добавленный обмен с тем же tradeid меняет состояние, которое отдаёт GetTradeStatus
+func (s *Server) AddTrades(apiKey string, trades ...steamapi.Trade) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := make([]steamapi.Trade, 0, len(trades)+len(s.trades[apiKey]))
+	for i := len(trades) - 1; i >= 0; i-- {
+		history = append(history, trades[i])
+	}
+	s.trades[apiKey] = append(history, s.trades[apiKey]...)
+}
+
+// Fail заставляет сервер ответить на следующие запросы указанными статусами, например 429 или 503
+func (s *Server) Fail(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// Requests - количество принятых запросов, включая неуспешные
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// UpdatePlayer меняет данные игрока под блокировкой сервера, например закрывает инвентарь между запросами
+func (s *Server) UpdatePlayer(steamID string, update func(p *Player)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.players[steamID]
+	if ok {
+		update(p)
+	}
+	return ok
+}
+
+// player возвращает копию данных игрока, чтобы обработчики не читали их параллельно с UpdatePlayer
+func (s *Server) player(steamID string) (Player, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.players[steamID]
+	if !ok {
+		return Player{}, false
+	}
+	cp := *p
+	cp.Assets = append([]json.RawMessage(nil), p.Assets...)
+	cp.Descriptions = append([]json.RawMessage(nil), p.Descriptions...)
+	return cp, true
+}
+
+func (s *Server) handle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		var status int
+		if len(s.failures) > 0 {
+			status, s.failures = s.failures[0], s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		if status != 0 {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(status)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// api дополнительно проверяет ключ, как это делает Web API
+func (s *Server) api(next http.HandlerFunc) http.HandlerFunc {
+	return s.handle(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if key == "" || (s.APIKey != "" && key != s.APIKey) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) playerBans(w http.ResponseWriter, r *http.Request) {
+	players := make([]steamapi.PlayerBans, 0)
+	for _, id := range strings.Split(r.URL.Query().Get("steamids"), ",") {
+		if p, ok := s.player(id); ok {
+			players = append(players, p.Bans)
+		}
+	}
+	writeJSON(w, map[string]interface{}{"players": players})
+}
+
+func (s *Server) playerSummaries(w http.ResponseWriter, r *http.Request) {
+	players := make([]steamapi.PlayerSummary, 0)
+	for _, id := range strings.Split(r.URL.Query().Get("steamids"), ",") {
+		if p, ok := s.player(id); ok {
+			players = append(players, p.Summary)
+		}
+	}
+	writeJSON(w, map[string]interface{}{"response": map[string]interface{}{"players": players}})
+}
+
+func (s *Server) steamLevel(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{}
+	if p, ok := s.player(r.URL.Query().Get("steamid")); ok {
+		response["player_level"] = p.Level
+	}
+	writeJSON(w, map[string]interface{}{"response": response})
+}
+
+// tradeHistory отдаёт историю обменов владельца ключа, как IEconService/GetTradeHistory
+func (s *Server) tradeHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	history, ok := s.trades[r.URL.Query().Get("key")]
+	trades := append([]steamapi.Trade{}, history...)
+	s.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Следующая страница начинается после обмена start_after_tradeid
+	if after := r.URL.Query().Get("start_after_tradeid"); after != "" {
+		for i, trade := range trades {
+			if trade.TradeID == after {
+				trades = trades[i+1:]
+				break
+			}
+		}
+	}
+	more := false
+	if max, err := strconv.Atoi(r.URL.Query().Get("max_trades")); err == nil && max > 0 && max < len(trades) {
+		trades = trades[:max]
+		more = true
+	}
+	writeJSON(w, map[string]interface{}{"response": map[string]interface{}{"trades": trades, "more": more}})
+}
+
+// tradeStatus отдаёт последнюю версию обмена tradeid из истории владельца ключа, как IEconService/GetTradeStatus
+func (s *Server) tradeStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	history, ok := s.trades[r.URL.Query().Get("key")]
+	s.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	trades := []steamapi.Trade{}
+	for _, trade := range history {
+		if trade.TradeID == r.URL.Query().Get("tradeid") {
+			trades = append(trades, trade)
+			break
+		}
+	}
+	writeJSON(w, map[string]interface{}{"response": map[string]interface{}{"trades": trades}})
+}
+
+func (s *Server) queryTime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	serverTime := time.Now().Add(s.TimeOffset).Unix()
+	writeJSON(w, map[string]interface{}{"response": map[string]interface{}{
+		"server_time":                           strconv.FormatInt(serverTime, 10),
+		"skew_tolerance_seconds":                "60",
+		"large_time_jink":                       "86400",
+		"probe_frequency_seconds":               3600,
+		"adjusted_time_probe_frequency_seconds": 300,
+		"hint_probe_frequency_seconds":          60,
+		"sync_timeout":                          60,
+		"try_again_seconds":                     900,
+		"max_attempts":                          3,
+	}})
+}
+
+// inventory отдаёт инвентарь страницами по count с курсором start_assetid, как Steam Community
+func (s *Server) inventory(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	p, ok := s.player(parts[1])
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if p.PrivateInventory {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count <= 0 {
+		count = len(p.Assets)
+	}
+	start := 0
+	if cursor := r.URL.Query().Get("start_assetid"); cursor != "" {
+		for i, raw := range p.Assets {
+			if assetID(raw) == cursor {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := start + count
+	if end > len(p.Assets) {
+		end = len(p.Assets)
+	}
+
+	page := steamapi.InventoryPage{
+		Assets:              p.Assets[start:end],
+		Descriptions:        p.Descriptions,
+		TotalInventoryCount: len(p.Assets),
+	}
+	if end < len(p.Assets) {
+		page.MoreItems = 1
+		page.LastAssetID = assetID(p.Assets[end-1])
+	}
+	writeJSON(w, page)
+}
+
+func assetID(raw json.RawMessage) string {
+	var asset struct {
+		AssetID string `json:"assetid"`
+	}
+	json.Unmarshal(raw, &asset)
+	return asset.AssetID
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,101 @@
+package steamguard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeURL = "https://api.steampowered.com/ITwoFactorService/QueryTime/v0001"
+	// ResyncInterval - как часто сверять время с Steam
+	ResyncInterval = time.Hour
+)
+
+// Clock - время серверов Steam: локальное время со смещением, полученным от ITwoFactorService/QueryTime
+type Clock struct {
+	URL        string
+	HTTPClient *http.Client
+
+	mu       sync.RWMutex
+	offset   time.Duration
+	syncedAt time.Time
+}
+
+func NewClock() *Clock {
+	return &Clock{URL: DefaultTimeURL, HTTPClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Now - локальное время с поправкой на смещение
+func (c *Clock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Now().Add(c.offset)
+}
+
+// Offset - на сколько время Steam опережает локальное
+func (c *Clock) Offset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offset
+}
+
+// SetOffset задаёт смещение вручную, например из сохранённого значения
+func (c *Clock) SetOffset(offset time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = offset
+	c.syncedAt = time.Now()
+}
+
+// Sync запрашивает время у Steam и пересчитывает смещение.
+// Время запроса делится пополам, чтобы учесть задержку сети
+func (c *Clock) Sync(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	sent := time.Now()
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("QueryTime вернул статус %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Response struct {
+			ServerTime string `json:"server_time"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	serverTime, err := strconv.ParseInt(result.Response.ServerTime, 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный server_time: %w", err)
+	}
+
+	received := time.Now()
+	local := sent.Add(received.Sub(sent) / 2)
+	c.SetOffset(time.Unix(serverTime, 0).Sub(local).Round(time.Second))
+	return nil
+}
+
+// SyncIfStale сверяет время, если с прошлой сверки прошло больше ResyncInterval
+func (c *Clock) SyncIfStale(ctx context.Context) error {
+	c.mu.RLock()
+	stale := c.syncedAt.IsZero() || time.Now().Sub(c.syncedAt) > ResyncInterval
+	c.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+	return c.Sync(ctx)
+}
@@ -0,0 +1,74 @@
+package steamguard
+
+import (
+	"context"
+	"cs-market/internal/steamapi/steamapitest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestClock(srv *steamapitest.Server) *Clock {
+	clock := NewClock()
+	clock.URL = srv.URL + "/ITwoFactorService/QueryTime/v0001"
+	return clock
+}
+
+// near - длительности совпадают с точностью до усечения server_time до секунд
+func near(a, b time.Duration) bool {
+	d := a - b
+	return d > -2*time.Second && d < 2*time.Second
+}
+
+func TestClockSync(t *testing.T) {
+	for _, offset := range []time.Duration{0, 90 * time.Second, -45 * time.Minute} {
+		srv := steamapitest.NewServer()
+		srv.TimeOffset = offset
+		clock := newTestClock(srv)
+
+		if err := clock.Sync(context.Background()); err != nil {
+			t.Fatalf("Sync: %v", err)
+		}
+		if !near(clock.Offset(), offset) {
+			t.Errorf("Offset() = %v, want %v", clock.Offset(), offset)
+		}
+		if diff := clock.Now().Sub(time.Now().Add(offset)); !near(diff, 0) {
+			t.Errorf("Now() расходится со временем сервера на %v", diff)
+		}
+		srv.Close()
+	}
+}
+
+func TestClockSyncError(t *testing.T) {
+	srv := steamapitest.NewServer()
+	defer srv.Close()
+	clock := newTestClock(srv)
+	clock.SetOffset(time.Minute)
+
+	srv.TimeOffset = time.Hour
+	srv.Fail(http.StatusServiceUnavailable)
+	if err := clock.Sync(context.Background()); err == nil {
+		t.Fatal("Sync: ожидалась ошибка при ответе 503")
+	}
+	if clock.Offset() != time.Minute {
+		t.Errorf("Offset() = %v после неудачной сверки, want 1m", clock.Offset())
+	}
+}
+
+func TestClockSyncIfStale(t *testing.T) {
+	srv := steamapitest.NewServer()
+	defer srv.Close()
+	srv.TimeOffset = 30 * time.Second
+	clock := newTestClock(srv)
+
+	ctx := context.Background()
+	if err := clock.SyncIfStale(ctx); err != nil {
+		t.Fatalf("SyncIfStale: %v", err)
+	}
+	if err := clock.SyncIfStale(ctx); err != nil {
+		t.Fatalf("SyncIfStale: %v", err)
+	}
+	if n := srv.Requests(); n != 1 {
+		t.Errorf("запросов к QueryTime: %d, want 1", n)
+	}
+}
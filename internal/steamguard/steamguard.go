@@ -0,0 +1,114 @@
+// Package steamguard - коды мобильного аутентификатора Steam Guard
+// и ключи мобильных подтверждений с поправкой на время серверов Steam
+package steamguard
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const (
+	// CodePeriod - время действия кода Steam Guard
+	CodePeriod   = 30 * time.Second
+	codeLength   = 5
+	codeAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+	maxTagLength = 32
+)
+
+// Теги ключей подтверждений
+const (
+	TagList    = "conf"
+	TagDetails = "details"
+	TagAllow   = "allow"
+	TagCancel  = "cancel"
+)
+
+var ErrInvalidSecret = errors.New("секрет должен быть в base64")
+
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// GenerateCode вычисляет 5-символьный код Steam Guard по shared_secret для момента t.
+// Это TOTP с периодом 30 секунд и алфавитом Steam вместо цифр
+func GenerateCode(sharedSecret string, t time.Time) (string, error) {
+	key, err := decodeSecret(sharedSecret)
+	if err != nil {
+		return "", err
+	}
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(t.Unix()/int64(CodePeriod/time.Second)))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(buf[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	full := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	code := make([]byte, codeLength)
+	for i := range code {
+		code[i] = codeAlphabet[full%uint32(len(codeAlphabet))]
+		full /= uint32(len(codeAlphabet))
+	}
+	return string(code), nil
+}
+
+// ConfirmationKey вычисляет ключ запроса мобильных подтверждений: HMAC-SHA1 от времени
+// и тега (conf, details, allow, cancel) на identity_secret в base64
+func ConfirmationKey(identitySecret string, t time.Time, tag string) (string, error) {
+	key, err := decodeSecret(identitySecret)
+	if err != nil {
+		return "", err
+	}
+	if len(tag) > maxTagLength {
+		tag = tag[:maxTagLength]
+	}
+
+	buf := make([]byte, 8, 8+len(tag))
+	binary.BigEndian.PutUint64(buf, uint64(t.Unix()))
+	buf = append(buf, tag...)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(buf)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Guard - аутентификатор аккаунта. Коды и ключи считаются по времени Clock,
+// чтобы расхождение локальных часов с серверами Steam не делало их недействительными
+type Guard struct {
+	SharedSecret   string
+	IdentitySecret string
+	Clock          *Clock
+}
+
+func New(sharedSecret, identitySecret string, clock *Clock) *Guard {
+	return &Guard{SharedSecret: sharedSecret, IdentitySecret: identitySecret, Clock: clock}
+}
+
+// Now - текущее время серверов Steam
+func (g *Guard) Now() time.Time {
+	if g.Clock == nil {
+		return time.Now()
+	}
+	return g.Clock.Now()
+}
+
+func (g *Guard) Code(t time.Time) (string, error) {
+	return GenerateCode(g.SharedSecret, t)
+}
+
+func (g *Guard) ConfirmationKey(t time.Time, tag string) (string, error) {
+	return ConfirmationKey(g.IdentitySecret, t, tag)
+}
+
+// CurrentCode - код для текущего времени Steam
+func (g *Guard) CurrentCode() (string, error) {
+	return g.Code(g.Now())
+}
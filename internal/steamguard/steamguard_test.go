@@ -0,0 +1,99 @@
+package steamguard
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret - ключ "12345678901234567890" из тестовых векторов RFC 6238 в base64
+const rfcSecret = "MTIzNDU2Nzg5MDEyMzQ1Njc4OTA="
+
+// Коды получены из значений TOTP-SHA1 RFC 6238 (94287082, 07081804, ...) переводом
+// полного 31-битного значения в алфавит Steam, младшие разряды первыми
+func TestGenerateCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "PV9M4"},
+		{1111111109, "PY4YB"},
+		{1234567890, "VHHQY"},
+		{2000000000, "9N776"},
+	}
+	for _, tt := range tests {
+		got, err := GenerateCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateCodePeriod(t *testing.T) {
+	start := time.Unix(1234567860, 0)
+	first, _ := GenerateCode(rfcSecret, start)
+	last, _ := GenerateCode(rfcSecret, start.Add(CodePeriod-time.Second))
+	next, _ := GenerateCode(rfcSecret, start.Add(CodePeriod))
+	if first != last {
+		t.Errorf("код сменился внутри периода: %s и %s", first, last)
+	}
+	if first == next {
+		t.Errorf("код не сменился в следующем периоде: %s", next)
+	}
+}
+
+func TestConfirmationKey(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{TagList, "uOi1VQCWymi7kGGUQW8vh0odoS0="},
+		{TagAllow, "+DyBq65u5ZJKRi75PrLwE9vklmc="},
+	}
+	for _, tt := range tests {
+		got, err := ConfirmationKey(rfcSecret, at, tt.tag)
+		if err != nil {
+			t.Fatalf("ConfirmationKey(%s): %v", tt.tag, err)
+		}
+		if got != tt.want {
+			t.Errorf("ConfirmationKey(%s) = %s, want %s", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmationKeyTruncatesTag(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	long := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	got, _ := ConfirmationKey(rfcSecret, at, long)
+	if want := "PTJdcVxjJnGwVPiEkAF5T4ttAhY="; got != want {
+		t.Errorf("ConfirmationKey с тегом длиннее %d символов = %s, want %s", maxTagLength, got, want)
+	}
+}
+
+func TestInvalidSecret(t *testing.T) {
+	if _, err := GenerateCode("не base64", time.Now()); err != ErrInvalidSecret {
+		t.Errorf("GenerateCode: err = %v, want ErrInvalidSecret", err)
+	}
+	if _, err := ConfirmationKey("", time.Now(), TagList); err != ErrInvalidSecret {
+		t.Errorf("ConfirmationKey: err = %v, want ErrInvalidSecret", err)
+	}
+}
+
+func TestGuardUsesClock(t *testing.T) {
+	clock := NewClock()
+	clock.SetOffset(-time.Hour)
+	g := New(rfcSecret, rfcSecret, clock)
+
+	now := g.Now()
+	if skew := time.Since(now) - time.Hour; skew < -time.Second || skew > time.Second {
+		t.Fatalf("Guard.Now() отстаёт на %v, want 1h", time.Since(now))
+	}
+	want, _ := GenerateCode(rfcSecret, now)
+	got, _ := g.Code(now)
+	if got != want {
+		t.Errorf("Guard.Code = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package tradebot
+
+import (
+	"context"
+	"errors"
+)
+
+// Состояния предложения обмена (ETradeOfferState)
+const (
+	OfferStateInvalid                = 1
+	OfferStateActive                 = 2
+	OfferStateAccepted               = 3
+	OfferStateCountered              = 4
+	OfferStateExpired                = 5
+	OfferStateCanceled               = 6
+	OfferStateDeclined               = 7
+	OfferStateInvalidItems           = 8
+	OfferStateNeedsConfirmation      = 9
+	OfferStateCanceledBySecondFactor = 10
+	OfferStateInEscrow               = 11
+)
+
+// OfferStateFinal - предложение больше не изменится
+func OfferStateFinal(state int) bool {
+	switch state {
+	case OfferStateActive, OfferStateNeedsConfirmation, OfferStateInEscrow:
+		return false
+	}
+	return true
+}
+
+const (
+	AppIDCS2     = 730
+	ContextIDCS2 = "2"
+)
+
+var (
+	ErrOfferNotFound = errors.New("предложение обмена не найдено")
+	ErrItemsNotFound = errors.New("предметы отсутствуют в инвентаре")
+	ErrBadTradeToken = errors.New("неверный токен ссылки на обмен")
+	ErrConfirmation  = errors.New("ошибка мобильного подтверждения")
+	ErrSession       = errors.New("сессия бота недействительна")
+)
+
+// Item - предмет в предложении обмена
+type Item struct {
+	AppID     int    `json:"appid"`
+	ContextID string `json:"contextid"`
+	AssetID   string `json:"assetid"`
+	Amount    int    `json:"amount,string"`
+}
+
+// Offer - предложение обмена в формате IEconService/GetTradeOffer
+type Offer struct {
+	TradeOfferID   string `json:"tradeofferid"`
+	AccountIDOther uint32 `json:"accountid_other"`
+	Message        string `json:"message"`
+	State          int    `json:"trade_offer_state"`
+	ItemsToGive    []Item `json:"items_to_give"`
+	ItemsToReceive []Item `json:"items_to_receive"`
+	IsOurOffer     bool   `json:"is_our_offer"`
+	TimeCreated    int64  `json:"time_created"`
+	TimeUpdated    int64  `json:"time_updated"`
+	TradeID        string `json:"tradeid"`
+	EscrowEndDate  int64  `json:"escrow_end_date"`
+}
+
+// NewOffer - создаваемое предложение обмена партнёру по его ссылке на обмен
+type NewOffer struct {
+	TradeURL       string
+	ItemsToGive    []Item
+	ItemsToReceive []Item
+	Message        string
+}
+
+// TradeAsset - предмет состоявшегося обмена и его новый assetid у получателя
+type TradeAsset struct {
+	AppID        int    `json:"appid"`
+	ContextID    string `json:"contextid"`
+	AssetID      string `json:"assetid"`
+	NewAssetID   string `json:"new_assetid"`
+	NewContextID string `json:"new_contextid"`
+}
+
+// TradeStatus - результат обмена в формате IEconService/GetTradeStatus
+type TradeStatus struct {
+	TradeID        string       `json:"tradeid"`
+	Status         int          `json:"status"`
+	AssetsGiven    []TradeAsset `json:"assets_given"`
+	AssetsReceived []TradeAsset `json:"assets_received"`
+}
+
+// Confirmation - ожидающее мобильное подтверждение. CreatorID - ID предложения обмена
+type Confirmation struct {
+	ID        string `json:"id"`
+	Nonce     string `json:"nonce"`
+	CreatorID string `json:"creator_id"`
+	Type      int    `json:"type"`
+}
+
+// ConfirmationAuth - подпись запроса мобильных подтверждений
+type ConfirmationAuth struct {
+	DeviceID string
+	SteamID  string
+	Time     int64
+	Key      string
+	Tag      string
+}
+
+// Session - веб-сессия бота
+type Session struct {
+	SteamID string
+	// AccessToken - токен Web API для методов IEconService
+	AccessToken string
+	// SessionID и SteamLoginSecure - куки steamcommunity.com для отправки предложений и подтверждений
+	SessionID        string
+	SteamLoginSecure string
+}
+
+// EconService - операции с предложениями обмена по образцу IEconService и мобильных подтверждений Steam
+type EconService interface {
+	SendTradeOffer(ctx context.Context, sess Session, offer NewOffer) (offerID string, needsConfirmation bool, err error)
+	GetTradeOffer(ctx context.Context, sess Session, offerID string) (Offer, error)
+	CancelTradeOffer(ctx context.Context, sess Session, offerID string) error
+	GetTradeStatus(ctx context.Context, sess Session, tradeID string) (TradeStatus, error)
+	GetConfirmations(ctx context.Context, sess Session, auth ConfirmationAuth) ([]Confirmation, error)
+	RespondConfirmation(ctx context.Context, sess Session, conf Confirmation, auth ConfirmationAuth, accept bool) error
+}
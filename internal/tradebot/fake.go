@@ -0,0 +1,326 @@
+package tradebot
+
+import (
+	"context"
+	"cs-market/internal/steamapi"
+	"cs-market/internal/steamguard"
+	"cs-market/internal/users"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FakeEconService - EconService в памяти процесса. Хранит инвентари и предложения обмена,
+// проверяет токены ссылок на обмен и ключи подтверждений, а действия партнёров
+// (принять, отклонить) выполняются методами Accept и Decline
+type FakeEconService struct {
+	// TimeOffset - на сколько часы фейкового Steam опережают локальные.
+	// Подтверждения с временем, отличающимся больше чем на период кода, отклоняются
+	TimeOffset time.Duration
+
+	mu           sync.Mutex
+	inventories  map[string]map[string]bool
+	tokens       map[string]string
+	secrets      map[string]string
+	offers       map[string]*fakeOffer
+	trades       map[string]TradeStatus
+	confirmNonce map[string]string
+	sendErrors   []error
+	nextID       int
+}
+
+type fakeOffer struct {
+	Offer
+	sender  string
+	partner string
+}
+
+func NewFakeEconService() *FakeEconService {
+	return &FakeEconService{
+		inventories:  make(map[string]map[string]bool),
+		tokens:       make(map[string]string),
+		secrets:      make(map[string]string),
+		offers:       make(map[string]*fakeOffer),
+		trades:       make(map[string]TradeStatus),
+		confirmNonce: make(map[string]string),
+		nextID:       1000,
+	}
+}
+
+// AddAccount регистрирует аккаунт с токеном ссылки на обмен и предметами в инвентаре
+func (f *FakeEconService) AddAccount(steamID, tradeToken string, assetIDs ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	inv := make(map[string]bool)
+	for _, id := range assetIDs {
+		inv[id] = true
+	}
+	f.inventories[steamID] = inv
+	f.tokens[steamID] = tradeToken
+}
+
+// AddBot регистрирует аккаунт бота и его identity_secret для проверки ключей подтверждений
+func (f *FakeEconService) AddBot(cfg BotConfig, assetIDs ...string) {
+	f.AddAccount(cfg.SteamID, "", assetIDs...)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.secrets[cfg.SteamID] = cfg.IdentitySecret
+}
+
+// Inventory возвращает assetid предметов аккаунта
+func (f *FakeEconService) Inventory(steamID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	for id := range f.inventories[steamID] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Offer возвращает предложение обмена
+func (f *FakeEconService) Offer(offerID string) (Offer, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.offers[offerID]
+	if !ok {
+		return Offer{}, false
+	}
+	return o.Offer, true
+}
+
+func (f *FakeEconService) newID() string {
+	f.nextID++
+	return strconv.Itoa(f.nextID)
+}
+
+// FailSend задаёт ошибки, которые по очереди вернут следующие вызовы SendTradeOffer
+func (f *FakeEconService) FailSend(errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sendErrors = append(f.sendErrors, errs...)
+}
+
+func (f *FakeEconService) SendTradeOffer(ctx context.Context, sess Session, offer NewOffer) (string, bool, error) {
+	partner, token, err := users.TradeURLParts(offer.TradeURL)
+	if err != nil {
+		return "", false, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sendErrors) > 0 {
+		err := f.sendErrors[0]
+		f.sendErrors = f.sendErrors[1:]
+		return "", false, err
+	}
+	if expected, ok := f.tokens[partner]; !ok || expected != token {
+		return "", false, ErrBadTradeToken
+	}
+	if !f.owns(sess.SteamID, offer.ItemsToGive) || !f.owns(partner, offer.ItemsToReceive) {
+		return "", false, ErrItemsNotFound
+	}
+
+	needsConfirmation := len(offer.ItemsToGive) > 0
+	state := OfferStateActive
+	if needsConfirmation {
+		state = OfferStateNeedsConfirmation
+	}
+	accountID, _ := strconv.ParseUint(partner, 10, 64)
+	now := time.Now().Unix()
+	o := &fakeOffer{
+		Offer: Offer{
+			TradeOfferID:   f.newID(),
+			AccountIDOther: uint32(accountID),
+			Message:        offer.Message,
+			State:          state,
+			ItemsToGive:    offer.ItemsToGive,
+			ItemsToReceive: offer.ItemsToReceive,
+			IsOurOffer:     true,
+			TimeCreated:    now,
+			TimeUpdated:    now,
+		},
+		sender:  sess.SteamID,
+		partner: partner,
+	}
+	f.offers[o.TradeOfferID] = o
+	if needsConfirmation {
+		f.confirmNonce[o.TradeOfferID] = f.newID()
+	}
+	return o.TradeOfferID, needsConfirmation, nil
+}
+
+func (f *FakeEconService) owns(steamID string, items []Item) bool {
+	for _, item := range items {
+		if !f.inventories[steamID][item.AssetID] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *FakeEconService) GetTradeOffer(ctx context.Context, sess Session, offerID string) (Offer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.offers[offerID]
+	if !ok || o.sender != sess.SteamID {
+		return Offer{}, ErrOfferNotFound
+	}
+	return o.Offer, nil
+}
+
+func (f *FakeEconService) CancelTradeOffer(ctx context.Context, sess Session, offerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.offers[offerID]
+	if !ok || o.sender != sess.SteamID || OfferStateFinal(o.State) {
+		return ErrOfferNotFound
+	}
+	f.setState(o, OfferStateCanceled)
+	return nil
+}
+
+func (f *FakeEconService) GetTradeStatus(ctx context.Context, sess Session, tradeID string) (TradeStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.trades[tradeID]
+	if !ok {
+		return TradeStatus{}, ErrOfferNotFound
+	}
+	return status, nil
+}
+
+func (f *FakeEconService) checkAuth(sess Session, auth ConfirmationAuth) error {
+	secret, ok := f.secrets[sess.SteamID]
+	if !ok || auth.SteamID != sess.SteamID {
+		return ErrSession
+	}
+	skew := time.Unix(auth.Time, 0).Sub(time.Now().Add(f.TimeOffset))
+	if skew > steamguard.CodePeriod || skew < -steamguard.CodePeriod {
+		return ErrConfirmation
+	}
+	key, err := steamguard.ConfirmationKey(secret, time.Unix(auth.Time, 0), auth.Tag)
+	if err != nil || key != auth.Key {
+		return ErrConfirmation
+	}
+	return nil
+}
+
+func (f *FakeEconService) GetConfirmations(ctx context.Context, sess Session, auth ConfirmationAuth) ([]Confirmation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkAuth(sess, auth); err != nil {
+		return nil, err
+	}
+
+	var list []Confirmation
+	for id, nonce := range f.confirmNonce {
+		if o := f.offers[id]; o.sender == sess.SteamID && o.State == OfferStateNeedsConfirmation {
+			list = append(list, Confirmation{ID: "c" + id, Nonce: nonce, CreatorID: id, Type: 2})
+		}
+	}
+	return list, nil
+}
+
+func (f *FakeEconService) RespondConfirmation(ctx context.Context, sess Session, conf Confirmation, auth ConfirmationAuth, accept bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkAuth(sess, auth); err != nil {
+		return err
+	}
+
+	o, ok := f.offers[conf.CreatorID]
+	if !ok || o.sender != sess.SteamID || o.State != OfferStateNeedsConfirmation || f.confirmNonce[conf.CreatorID] != conf.Nonce {
+		return ErrConfirmation
+	}
+	delete(f.confirmNonce, conf.CreatorID)
+	if accept {
+		f.setState(o, OfferStateActive)
+	} else {
+		f.setState(o, OfferStateCanceledBySecondFactor)
+	}
+	return nil
+}
+
+// Accept - партнёр принимает предложение: предметы переходят к новым владельцам с новыми assetid
+func (f *FakeEconService) Accept(offerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.offers[offerID]
+	if !ok || o.State != OfferStateActive {
+		return ErrOfferNotFound
+	}
+	if !f.owns(o.sender, o.ItemsToGive) || !f.owns(o.partner, o.ItemsToReceive) {
+		f.setState(o, OfferStateInvalidItems)
+		return ErrItemsNotFound
+	}
+
+	status := TradeStatus{TradeID: f.newID(), Status: steamapi.TradeStatusComplete}
+	status.AssetsGiven = f.move(o.sender, o.partner, o.ItemsToGive)
+	status.AssetsReceived = f.move(o.partner, o.sender, o.ItemsToReceive)
+	f.trades[status.TradeID] = status
+
+	o.TradeID = status.TradeID
+	f.setState(o, OfferStateAccepted)
+	return nil
+}
+
+// Decline - партнёр отклоняет предложение
+func (f *FakeEconService) Decline(offerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.offers[offerID]
+	if !ok || o.State != OfferStateActive {
+		return ErrOfferNotFound
+	}
+	f.setState(o, OfferStateDeclined)
+	return nil
+}
+
+// Rollback - Steam откатывает завершённый обмен: предметы возвращаются прежним владельцам с новыми assetid
+func (f *FakeEconService) Rollback(tradeID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.trades[tradeID]
+	if !ok || status.Status != steamapi.TradeStatusComplete {
+		return ErrOfferNotFound
+	}
+	for _, o := range f.offers {
+		if o.TradeID != tradeID {
+			continue
+		}
+		for _, asset := range status.AssetsGiven {
+			delete(f.inventories[o.partner], asset.NewAssetID)
+			f.inventories[o.sender][f.newID()] = true
+		}
+		for _, asset := range status.AssetsReceived {
+			delete(f.inventories[o.sender], asset.NewAssetID)
+			f.inventories[o.partner][f.newID()] = true
+		}
+	}
+	status.Status = steamapi.TradeStatusProtectionRollback
+	f.trades[tradeID] = status
+	return nil
+}
+
+func (f *FakeEconService) move(from, to string, items []Item) []TradeAsset {
+	assets := make([]TradeAsset, 0, len(items))
+	for _, item := range items {
+		newID := f.newID()
+		delete(f.inventories[from], item.AssetID)
+		f.inventories[to][newID] = true
+		assets = append(assets, TradeAsset{
+			AppID:        item.AppID,
+			ContextID:    item.ContextID,
+			AssetID:      item.AssetID,
+			NewAssetID:   newID,
+			NewContextID: item.ContextID,
+		})
+	}
+	return assets
+}
+
+func (f *FakeEconService) setState(o *fakeOffer, state int) {
+	o.State = state
+	o.TimeUpdated = time.Now().Unix()
+}
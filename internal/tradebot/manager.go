@@ -0,0 +1,398 @@
+package tradebot
+
+import (
+	"context"
+	"cs-market/internal/steamguard"
+	"cs-market/internal/users"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPollInterval = 30 * time.Second
+	requestTimeout      = 30 * time.Second
+)
+
+var ErrNoBots = errors.New("нет доступных ботов")
+
+// Handler получает предложение обмена после смены его состояния
+type Handler func(ctx context.Context, offer TradeOffer) error
+
+// Guard - мобильный аутентификатор бота: коды Steam Guard и ключи подтверждений
+type Guard interface {
+	Now() time.Time
+	Code(t time.Time) (string, error)
+	ConfirmationKey(t time.Time, tag string) (string, error)
+}
+
+// Коды и ключи считает только steamguard, менеджер работает с ним через Guard
+var _ Guard = (*steamguard.Guard)(nil)
+
+// Bot - аккаунт бота с сессией и мобильным аутентификатором
+type Bot struct {
+	Config  BotConfig
+	Session Session
+	Guard   Guard
+}
+
+// NewBot создаёт бота с аутентификатором steamguard. Коды считаются по времени clock
+func NewBot(cfg BotConfig, clock *steamguard.Clock) *Bot {
+	return &Bot{
+		Config: cfg,
+		Session: Session{
+			SteamID:          cfg.SteamID,
+			AccessToken:      cfg.AccessToken,
+			SessionID:        cfg.SessionID,
+			SteamLoginSecure: cfg.SteamLoginSecure,
+		},
+		Guard: steamguard.New(cfg.SharedSecret, cfg.IdentitySecret, clock),
+	}
+}
+
+// Manager отправляет предложения обмена от имени ботов, подтверждает их
+// и сообщает обработчику об изменении их состояния
+type Manager struct {
+	db      *gorm.DB
+	econ    EconService
+	bots    map[string]*Bot
+	order   []string
+	handler Handler
+	// clock - время Steam для кодов ботов, сверяется при опросе
+	clock *steamguard.Clock
+
+	mu   sync.Mutex
+	next int
+}
+
+func NewManager(db *gorm.DB, econ EconService, bots []*Bot, handler Handler) *Manager {
+	m := &Manager{db: db, econ: econ, bots: make(map[string]*Bot), handler: handler}
+	for _, bot := range bots {
+		m.bots[bot.Config.SteamID] = bot
+		m.order = append(m.order, bot.Config.SteamID)
+	}
+	return m
+}
+
+var manager *Manager
+
+// Init загружает ботов из JSON-файла TRADEBOT_CONFIG и запускает опрос предложений.
+// Без TRADEBOT_CONFIG торговые боты отключены, и заказы передаются продавцом вручную
+func Init(db *gorm.DB, handler Handler) error {
+	path := os.Getenv("TRADEBOT_CONFIG")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var configs []BotConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return fmt.Errorf("ошибка разбора %s: %w", path, err)
+	}
+	if len(configs) == 0 {
+		return ErrNoBots
+	}
+
+	clock := steamguard.NewClock()
+	if apiURL := os.Getenv("STEAM_API_URL"); apiURL != "" {
+		clock.URL = apiURL + "/ITwoFactorService/QueryTime/v0001"
+	}
+	if err := clock.Sync(context.Background()); err != nil {
+		log.Println("Ошибка сверки времени со Steam, используется локальное:", err)
+	}
+
+	bots := make([]*Bot, 0, len(configs))
+	for _, cfg := range configs {
+		bots = append(bots, NewBot(cfg, clock))
+	}
+
+	interval := defaultPollInterval
+	if d, err := time.ParseDuration(os.Getenv("TRADEBOT_POLL_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+
+	m := NewManager(db, NewSteamEconService(), bots, handler)
+	m.clock = clock
+	SetDefault(m)
+	manager.Start(interval)
+	log.Printf("Торговые боты запущены: %d", len(bots))
+	return nil
+}
+
+// SetDefault задаёт общий менеджер, например с FakeEconService
+func SetDefault(m *Manager) {
+	manager = m
+}
+
+// Default возвращает общий менеджер или nil, если боты отключены
+func Default() *Manager {
+	return manager
+}
+
+// Enabled - заказы доставляются торговыми ботами
+func Enabled() bool {
+	return manager != nil
+}
+
+// pickBot выбирает ботов по кругу
+func (m *Manager) pickBot() (*Bot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.order) == 0 {
+		return nil, ErrNoBots
+	}
+	bot := m.bots[m.order[m.next%len(m.order)]]
+	m.next++
+	return bot, nil
+}
+
+// RequestDeposit отправляет продавцу предложение передать боту проданный предмет
+func (m *Manager) RequestDeposit(ctx context.Context, orderID uint, sellerTradeURL, assetID string) (*TradeOffer, error) {
+	bot, err := m.pickBot()
+	if err != nil {
+		return nil, err
+	}
+	return m.send(ctx, bot, orderID, KindDeposit, sellerTradeURL, assetID,
+		NewOffer{ItemsToReceive: []Item{cs2Item(assetID)}, Message: fmt.Sprintf("Заказ #%d: передача проданного предмета", orderID)})
+}
+
+// SendDelivery отправляет покупателю предмет, полученный ботом по заказу
+func (m *Manager) SendDelivery(ctx context.Context, orderID uint, botSteamID, buyerTradeURL, assetID string) (*TradeOffer, error) {
+	bot, ok := m.bots[botSteamID]
+	if !ok {
+		return nil, ErrNoBots
+	}
+	return m.send(ctx, bot, orderID, KindDelivery, buyerTradeURL, assetID,
+		NewOffer{ItemsToGive: []Item{cs2Item(assetID)}, Message: fmt.Sprintf("Заказ #%d: доставка покупки", orderID)})
+}
+
+// SendReturn возвращает продавцу предмет, который не удалось доставить покупателю
+func (m *Manager) SendReturn(ctx context.Context, orderID uint, botSteamID, sellerTradeURL, assetID string) (*TradeOffer, error) {
+	bot, ok := m.bots[botSteamID]
+	if !ok {
+		return nil, ErrNoBots
+	}
+	return m.send(ctx, bot, orderID, KindReturn, sellerTradeURL, assetID,
+		NewOffer{ItemsToGive: []Item{cs2Item(assetID)}, Message: fmt.Sprintf("Заказ #%d: возврат предмета", orderID)})
+}
+
+func cs2Item(assetID string) Item {
+	return Item{AppID: AppIDCS2, ContextID: ContextIDCS2, AssetID: assetID, Amount: 1}
+}
+
+func (m *Manager) send(ctx context.Context, bot *Bot, orderID uint, kind, tradeURL, assetID string, offer NewOffer) (*TradeOffer, error) {
+	partner, _, err := users.TradeURLParts(tradeURL)
+	if err != nil {
+		return nil, err
+	}
+	offer.TradeURL = tradeURL
+
+	offerID, needsConfirmation, err := m.econ.SendTradeOffer(ctx, bot.Session, offer)
+	if err != nil {
+		return nil, err
+	}
+
+	record := TradeOffer{
+		OfferID:        offerID,
+		BotSteamID:     bot.Config.SteamID,
+		OrderID:        orderID,
+		Kind:           kind,
+		PartnerSteamID: partner,
+		AssetID:        assetID,
+		State:          OfferStateActive,
+	}
+	if needsConfirmation {
+		record.State = OfferStateNeedsConfirmation
+	}
+	if err := m.db.Create(&record).Error; err != nil {
+		// Без записи предложение не отслеживается, а повторная обработка отправит новое - отменяем это
+		if cancelErr := m.econ.CancelTradeOffer(ctx, bot.Session, offerID); cancelErr != nil {
+			log.Printf("Не удалось отменить неучтённое предложение %s по заказу %d: %v", offerID, orderID, cancelErr)
+		}
+		return nil, err
+	}
+
+	if needsConfirmation {
+		if err := m.confirm(ctx, bot, offerID); err != nil {
+			// Подтверждение повторится при следующем опросе
+			log.Printf("Ошибка подтверждения предложения %s: %v", offerID, err)
+		}
+	}
+	return &record, nil
+}
+
+// confirm находит мобильное подтверждение предложения и принимает его
+func (m *Manager) confirm(ctx context.Context, bot *Bot, offerID string) error {
+	auth, err := m.confirmationAuth(bot, steamguard.TagList)
+	if err != nil {
+		return err
+	}
+	list, err := m.econ.GetConfirmations(ctx, bot.Session, auth)
+	if err != nil {
+		return err
+	}
+
+	for _, conf := range list {
+		if conf.CreatorID != offerID {
+			continue
+		}
+		auth, err := m.confirmationAuth(bot, steamguard.TagAllow)
+		if err != nil {
+			return err
+		}
+		return m.econ.RespondConfirmation(ctx, bot.Session, conf, auth, true)
+	}
+	return fmt.Errorf("%w: предложение %s не найдено среди подтверждений", ErrConfirmation, offerID)
+}
+
+func (m *Manager) confirmationAuth(bot *Bot, tag string) (ConfirmationAuth, error) {
+	now := bot.Guard.Now()
+	key, err := bot.Guard.ConfirmationKey(now, tag)
+	if err != nil {
+		return ConfirmationAuth{}, err
+	}
+	return ConfirmationAuth{
+		DeviceID: bot.Config.DeviceID,
+		SteamID:  bot.Config.SteamID,
+		Time:     now.Unix(),
+		Key:      key,
+		Tag:      tag,
+	}, nil
+}
+
+// CancelOrderOffers отзывает незавершённые предложения по заказу
+func (m *Manager) CancelOrderOffers(ctx context.Context, orderID uint) error {
+	var offers []TradeOffer
+	err := m.db.Where("order_id = ? AND state IN ?", orderID,
+		[]int{OfferStateActive, OfferStateNeedsConfirmation}).Find(&offers).Error
+	if err != nil {
+		return err
+	}
+
+	for _, offer := range offers {
+		bot, ok := m.bots[offer.BotSteamID]
+		if !ok {
+			continue
+		}
+		if err := m.econ.CancelTradeOffer(ctx, bot.Session, offer.OfferID); err != nil {
+			log.Printf("Ошибка отмены предложения %s: %v", offer.OfferID, err)
+		}
+	}
+	return nil
+}
+
+// Poll обновляет состояние незавершённых предложений и передаёт изменившиеся обработчику.
+// Предложения, обработчик которых завершился ошибкой, передаются ему повторно
+func (m *Manager) Poll(ctx context.Context) {
+	if m.clock != nil {
+		if err := m.clock.SyncIfStale(ctx); err != nil {
+			log.Println("Ошибка сверки времени со Steam:", err)
+		}
+	}
+
+	var offers []TradeOffer
+	err := m.db.Where("state IN ? OR handle_pending", []int{OfferStateActive, OfferStateNeedsConfirmation, OfferStateInEscrow}).
+		Order("id").Find(&offers).Error
+	if err != nil {
+		log.Println("Ошибка получения предложений обмена:", err)
+		return
+	}
+
+	for _, offer := range offers {
+		offerCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		err := m.refresh(offerCtx, offer)
+		cancel()
+		if err != nil {
+			log.Printf("Ошибка обновления предложения %s: %v", offer.OfferID, err)
+		}
+	}
+}
+
+// TradeStatus возвращает состояние обмена по предложению, отправленному ботом botSteamID.
+// По нему видно, не откатил ли Steam обмен во время защиты
+func (m *Manager) TradeStatus(ctx context.Context, botSteamID, tradeID string) (TradeStatus, error) {
+	bot, ok := m.bots[botSteamID]
+	if !ok {
+		return TradeStatus{}, ErrNoBots
+	}
+	return m.econ.GetTradeStatus(ctx, bot.Session, tradeID)
+}
+
+func (m *Manager) refresh(ctx context.Context, offer TradeOffer) error {
+	if offer.HandlePending {
+		return m.handle(ctx, offer)
+	}
+
+	bot, ok := m.bots[offer.BotSteamID]
+	if !ok {
+		return ErrNoBots
+	}
+
+	current, err := m.econ.GetTradeOffer(ctx, bot.Session, offer.OfferID)
+	if err != nil {
+		return err
+	}
+	if current.State == OfferStateNeedsConfirmation {
+		return m.confirm(ctx, bot, offer.OfferID)
+	}
+	if current.State == offer.State {
+		return nil
+	}
+
+	updates := map[string]interface{}{"state": current.State, "trade_id": current.TradeID, "handle_pending": true}
+	if current.State == OfferStateAccepted && current.TradeID != "" {
+		status, err := m.econ.GetTradeStatus(ctx, bot.Session, current.TradeID)
+		if err != nil {
+			return err
+		}
+		assets := status.AssetsReceived
+		if offer.Kind != KindDeposit {
+			assets = status.AssetsGiven
+		}
+		for _, asset := range assets {
+			if asset.AssetID == offer.AssetID {
+				updates["new_asset_id"] = asset.NewAssetID
+				offer.NewAssetID = asset.NewAssetID
+			}
+		}
+	}
+
+	// Новое состояние сохраняется вместе с отметкой HandlePending, которая снимается только после успешной обработки,
+	// поэтому ошибка обработчика не оставляет предложение в итоговом состоянии без реакции заказа
+	if err := m.db.Model(&offer).Updates(updates).Error; err != nil {
+		return err
+	}
+	offer.State = current.State
+	offer.TradeID = current.TradeID
+	offer.HandlePending = true
+
+	return m.handle(ctx, offer)
+}
+
+// handle передаёт предложение обработчику и снимает отметку HandlePending после успешной обработки
+func (m *Manager) handle(ctx context.Context, offer TradeOffer) error {
+	if m.handler != nil {
+		if err := m.handler(ctx, offer); err != nil {
+			return err
+		}
+	}
+	return m.db.Model(&offer).Update("handle_pending", false).Error
+}
+
+func (m *Manager) Start(interval time.Duration) {
+	go func() {
+		for {
+			m.Poll(context.Background())
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package tradebot
+
+import "time"
+
+// Назначение предложения обмена
+const (
+	// KindDeposit - бот забирает проданный предмет у продавца
+	KindDeposit = "deposit"
+	// KindDelivery - бот передаёт предмет покупателю
+	KindDelivery = "delivery"
+	// KindReturn - бот возвращает предмет продавцу, если доставка не состоялась
+	KindReturn = "return"
+)
+
+// TradeOffer - отправленное ботом предложение обмена по заказу
+type TradeOffer struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	OfferID        string `json:"offer_id" gorm:"uniqueIndex;not null"`
+	BotSteamID     string `json:"bot_steam_id" gorm:"not null;index"`
+	OrderID        uint   `json:"order_id" gorm:"not null;index"`
+	Kind           string `json:"kind" gorm:"not null"`
+	PartnerSteamID string `json:"partner_steam_id" gorm:"not null"`
+	AssetID        string `json:"asset_id" gorm:"not null"`
+	// NewAssetID - assetid предмета у получателя после обмена
+	NewAssetID string `json:"new_asset_id"`
+	State      int    `json:"state" gorm:"not null;index"`
+	TradeID    string `json:"trade_id"`
+	// HandlePending - состояние изменилось, но обработчик ещё не отработал успешно, опрос повторит вызов
+	HandlePending bool      `json:"-" gorm:"not null;default:false;index"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Accepted - обмен состоялся
+func (o *TradeOffer) Accepted() bool {
+	return o.State == OfferStateAccepted
+}
+
+// Failed - предложение закрыто без обмена
+func (o *TradeOffer) Failed() bool {
+	return OfferStateFinal(o.State) && o.State != OfferStateAccepted
+}
+
+// BotConfig - учётные данные бота из файла TRADEBOT_CONFIG
+type BotConfig struct {
+	Name             string `json:"name"`
+	SteamID          string `json:"steam_id"`
+	SharedSecret     string `json:"shared_secret"`
+	IdentitySecret   string `json:"identity_secret"`
+	DeviceID         string `json:"device_id"`
+	AccessToken      string `json:"access_token"`
+	SessionID        string `json:"session_id"`
+	SteamLoginSecure string `json:"steam_login_secure"`
+}
@@ -0,0 +1,264 @@
+package tradebot
+
+import (
+	"context"
+	"cs-market/internal/users"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	steamAPIURL       = "https://api.steampowered.com"
+	steamCommunityURL = "https://steamcommunity.com"
+)
+
+// SteamEconService - EconService поверх Steam Web API и steamcommunity.com
+type SteamEconService struct {
+	APIURL       string
+	CommunityURL string
+	HTTPClient   *http.Client
+}
+
+func NewSteamEconService() *SteamEconService {
+	return &SteamEconService{
+		APIURL:       steamAPIURL,
+		CommunityURL: steamCommunityURL,
+		HTTPClient:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// tradeOfferAsset - предмет в json_tradeoffer при отправке предложения
+type tradeOfferAsset struct {
+	AppID     int    `json:"appid"`
+	ContextID string `json:"contextid"`
+	AssetID   string `json:"assetid"`
+	Amount    int    `json:"amount"`
+}
+
+type tradeOfferSide struct {
+	Assets   []tradeOfferAsset `json:"assets"`
+	Currency []struct{}        `json:"currency"`
+	Ready    bool              `json:"ready"`
+}
+
+func offerSide(items []Item) tradeOfferSide {
+	side := tradeOfferSide{Assets: make([]tradeOfferAsset, 0, len(items)), Currency: []struct{}{}}
+	for _, item := range items {
+		amount := item.Amount
+		if amount == 0 {
+			amount = 1
+		}
+		side.Assets = append(side.Assets, tradeOfferAsset{AppID: item.AppID, ContextID: item.ContextID, AssetID: item.AssetID, Amount: amount})
+	}
+	return side
+}
+
+func (s *SteamEconService) SendTradeOffer(ctx context.Context, sess Session, offer NewOffer) (string, bool, error) {
+	partner, token, err := users.TradeURLParts(offer.TradeURL)
+	if err != nil {
+		return "", false, err
+	}
+
+	tradeOffer, _ := json.Marshal(map[string]interface{}{
+		"newversion": true,
+		"version":    2,
+		"me":         offerSide(offer.ItemsToGive),
+		"them":       offerSide(offer.ItemsToReceive),
+	})
+	createParams, _ := json.Marshal(map[string]string{"trade_offer_access_token": token})
+	form := url.Values{
+		"sessionid":                 {sess.SessionID},
+		"serverid":                  {"1"},
+		"partner":                   {partner},
+		"tradeoffermessage":         {offer.Message},
+		"json_tradeoffer":           {string(tradeOffer)},
+		"captcha":                   {""},
+		"trade_offer_create_params": {string(createParams)},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.CommunityURL+"/tradeoffer/new/send", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", offer.TradeURL)
+	s.addCookies(req, sess)
+
+	// Отказ Steam приходит со статусом 500 и текстом ошибки в теле, поэтому ответ разбирается здесь, а не в do
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("запрос к Steam %s: %w", req.URL.Path, unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", false, ErrSession
+	}
+
+	var result struct {
+		TradeOfferID            string `json:"tradeofferid"`
+		NeedsMobileConfirmation bool   `json:"needs_mobile_confirmation"`
+		StrError                string `json:"strError"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return "", false, err
+	}
+	if result.TradeOfferID == "" {
+		if err := sendOfferError(result.StrError); err != nil {
+			return "", false, err
+		}
+		return "", false, fmt.Errorf("Steam %s вернул статус %d: %s", req.URL.Path, resp.StatusCode, result.StrError)
+	}
+	return result.TradeOfferID, result.NeedsMobileConfirmation, nil
+}
+
+// sendOfferError сопоставляет окончательные отказы Steam при отправке предложения ошибкам пакета
+// по коду EResult в конце текста: 15 (AccessDenied) - неверный токен ссылки или закрытый обмен,
+// 26 (Revoked) - предмета уже нет в инвентаре. Остальные отказы считаются временными
+func sendOfferError(strError string) error {
+	strError = strings.TrimSpace(strError)
+	switch {
+	case strings.HasSuffix(strError, "(15)"):
+		return ErrBadTradeToken
+	case strings.HasSuffix(strError, "(26)"):
+		return ErrItemsNotFound
+	}
+	return nil
+}
+
+func (s *SteamEconService) GetTradeOffer(ctx context.Context, sess Session, offerID string) (Offer, error) {
+	query := url.Values{"access_token": {sess.AccessToken}, "tradeofferid": {offerID}}
+	var result struct {
+		Response struct {
+			Offer *Offer `json:"offer"`
+		} `json:"response"`
+	}
+	if err := s.get(ctx, s.APIURL+"/IEconService/GetTradeOffer/v1/?"+query.Encode(), sess, &result); err != nil {
+		return Offer{}, err
+	}
+	if result.Response.Offer == nil {
+		return Offer{}, ErrOfferNotFound
+	}
+	return *result.Response.Offer, nil
+}
+
+func (s *SteamEconService) CancelTradeOffer(ctx context.Context, sess Session, offerID string) error {
+	form := url.Values{"access_token": {sess.AccessToken}, "tradeofferid": {offerID}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.APIURL+"/IEconService/CancelTradeOffer/v1/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return s.do(req, nil)
+}
+
+func (s *SteamEconService) GetTradeStatus(ctx context.Context, sess Session, tradeID string) (TradeStatus, error) {
+	query := url.Values{"access_token": {sess.AccessToken}, "tradeid": {tradeID}, "get_descriptions": {"0"}}
+	var result struct {
+		Response struct {
+			Trades []TradeStatus `json:"trades"`
+		} `json:"response"`
+	}
+	if err := s.get(ctx, s.APIURL+"/IEconService/GetTradeStatus/v1/?"+query.Encode(), sess, &result); err != nil {
+		return TradeStatus{}, err
+	}
+	if len(result.Response.Trades) != 1 {
+		return TradeStatus{}, fmt.Errorf("обмен %s не найден", tradeID)
+	}
+	return result.Response.Trades[0], nil
+}
+
+func (s *SteamEconService) GetConfirmations(ctx context.Context, sess Session, auth ConfirmationAuth) ([]Confirmation, error) {
+	var result struct {
+		Success bool           `json:"success"`
+		Conf    []Confirmation `json:"conf"`
+	}
+	if err := s.get(ctx, s.CommunityURL+"/mobileconf/getlist?"+confirmationQuery(auth).Encode(), sess, &result); err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, ErrConfirmation
+	}
+	return result.Conf, nil
+}
+
+func (s *SteamEconService) RespondConfirmation(ctx context.Context, sess Session, conf Confirmation, auth ConfirmationAuth, accept bool) error {
+	op := "allow"
+	if !accept {
+		op = "cancel"
+	}
+	query := confirmationQuery(auth)
+	query.Set("op", op)
+	query.Set("cid", conf.ID)
+	query.Set("ck", conf.Nonce)
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := s.get(ctx, s.CommunityURL+"/mobileconf/ajaxop?"+query.Encode(), sess, &result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return ErrConfirmation
+	}
+	return nil
+}
+
+func confirmationQuery(auth ConfirmationAuth) url.Values {
+	return url.Values{
+		"p":   {auth.DeviceID},
+		"a":   {auth.SteamID},
+		"k":   {auth.Key},
+		"t":   {strconv.FormatInt(auth.Time, 10)},
+		"m":   {"react"},
+		"tag": {auth.Tag},
+	}
+}
+
+func (s *SteamEconService) addCookies(req *http.Request, sess Session) {
+	req.AddCookie(&http.Cookie{Name: "sessionid", Value: sess.SessionID})
+	req.AddCookie(&http.Cookie{Name: "steamLoginSecure", Value: sess.SteamLoginSecure})
+}
+
+func (s *SteamEconService) get(ctx context.Context, target string, sess Session, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	s.addCookies(req, sess)
+	return s.do(req, v)
+}
+
+// do выполняет запрос и декодирует ответ. Адрес запроса с токенами в ошибку не попадает
+func (s *SteamEconService) do(req *http.Request, v interface{}) error {
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("запрос к Steam %s: %w", req.URL.Path, unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return ErrSession
+	case resp.StatusCode != http.StatusOK:
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("Steam %s вернул статус %d", req.URL.Path, resp.StatusCode)
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func unwrapURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
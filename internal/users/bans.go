@@ -0,0 +1,59 @@
+package users
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// activeBans - блокировки, которые не сняты и не истекли
+func activeBans(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now())
+}
+
+// ActiveBans возвращает действующие ограничения пользователя
+func ActiveBans(db *gorm.DB, userID uint) ([]Ban, error) {
+	bans := make([]Ban, 0)
+	err := activeBans(db, userID).Order("created_at DESC").Find(&bans).Error
+	return bans, err
+}
+
+// ActiveBan возвращает действующую блокировку в указанной области или nil, если её нет.
+// Из нескольких блокировок возвращается та, что закончится позже
+func ActiveBan(db *gorm.DB, userID uint, scope string) (*Ban, error) {
+	var ban Ban
+	err := activeBans(db, userID).Where("scope = ?", scope).
+		Order("expires_at DESC NULLS FIRST").
+		First(&ban).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ban, nil
+}
+
+// MigrateLegacyBans переносит блокировки из полей users.banned_at и users.ban_reason
+// в таблицу блокировок с областью login и удаляет эти поля. Без них ничего не делает
+func MigrateLegacyBans(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&User{}, "banned_at") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO bans (user_id, scope, reason, issued_by, created_at)
+			SELECT id, ?, COALESCE(NULLIF(ban_reason, ''), ?), 0, banned_at
+			FROM users WHERE banned_at IS NOT NULL`, BanScopeLogin, "Блокировка аккаунта").Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&User{}, "banned_at"); err != nil {
+			return err
+		}
+		if tx.Migrator().HasColumn(&User{}, "ban_reason") {
+			return tx.Migrator().DropColumn(&User{}, "ban_reason")
+		}
+		return nil
+	})
+}
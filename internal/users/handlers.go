@@ -1,21 +1,32 @@
 package users
 
 import (
+	"context"
+	"cs-market/internal/steamapi"
 	"cs-market/internal/storage"
+	"errors"
+	"log"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// steamAPIKeyPattern - ключ Steam Web API: 32 шестнадцатеричных символа
+var steamAPIKeyPattern = regexp.MustCompile(`^[0-9A-Fa-f]{32}$`)
+
 // @Security BearerAuth
 // GetUserProfileHandler godoc
 // @Summary Получение профиля пользователя
-// @Description Получение информации о своём профиле пользователя
+// @Description Получение информации о своём профиле пользователя и действующих ограничениях аккаунта
 // @Tags users
 // @Accept json
 // @Produce json
-// @Success 200 {object} response.UserResponse "Информация о профиле"
+// @Success 200 {object} ProfileResponse "Информация о профиле"
 // @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения ограничений"
 // @Router /profile [get]
 func GetUserProfileHandler(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -26,5 +37,128 @@ func GetUserProfileHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	profile, err := profileResponse(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения ограничений"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Security BearerAuth
+// UpdateTradeURLHandler godoc
+// @Summary Ссылка на обмен
+// @Description Сохраняет ссылку на обмен Steam. partner должен соответствовать SteamID пользователя, token - 8 символов
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body TradeURLRequest true "Ссылка на обмен"
+// @Success 200 {object} ProfileResponse "Профиль с сохранённой ссылкой"
+// @Failure 400 {object} response.ErrorResponse "Некорректная ссылка на обмен"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Router /profile/trade-url [put]
+func UpdateTradeURLHandler(c *gin.Context) {
+	var input TradeURLRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	var user User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	tradeURL, err := ParseTradeURL(strings.TrimSpace(input.TradeURL), user.SteamID)
+	if err != nil {
+		if errors.Is(err, ErrTradeURLWrongOwner) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ссылка на обмен принадлежит другому аккаунту"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректная ссылка на обмен"})
+		}
+		return
+	}
+
+	if err := storage.DB.Model(&user).Update("trade_url", tradeURL).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения ссылки на обмен"})
+		return
+	}
+	user.TradeURL = tradeURL
+
+	profile, err := profileResponse(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения ограничений"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Security BearerAuth
+// UpdateSteamAPIKeyHandler godoc
+// @Summary Ключ Steam Web API
+// @Description Сохраняет ключ Steam Web API продавца. По истории обменов этого ключа проверяется передача предметов в P2P-заказах, без него продавать можно только через торговых ботов
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body SteamAPIKeyRequest true "Ключ Steam Web API"
+// @Success 200 {object} ProfileResponse "Профиль"
+// @Failure 400 {object} response.ErrorResponse "Недействительный ключ Steam Web API"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 502 {object} response.ErrorResponse "Steam недоступен"
+// @Router /profile/steam-api-key [put]
+func UpdateSteamAPIKeyHandler(c *gin.Context) {
+	var input SteamAPIKeyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+	key := strings.ToUpper(strings.TrimSpace(input.APIKey))
+	if !steamAPIKeyPattern.MatchString(key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Недействительный ключ Steam Web API"})
+		return
+	}
+
+	var user User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	// Ключ проверяется запросом истории обменов, которая понадобится для проверки передачи
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	if _, err := steamapi.Default().TradeHistory(ctx, key, 1); err != nil {
+		if errors.Is(err, steamapi.ErrInvalidAPIKey) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Недействительный ключ Steam Web API"})
+		} else {
+			log.Printf("Ошибка проверки ключа Steam Web API пользователя %s: %v", user.SteamID, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Steam недоступен"})
+		}
+		return
+	}
+
+	user.SteamAPIKey = EncryptedString(key)
+	if err := storage.DB.Model(&user).Update("steam_api_key", user.SteamAPIKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения ключа"})
+		return
+	}
+
+	profile, err := profileResponse(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения ограничений"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func profileResponse(user User) (ProfileResponse, error) {
+	restrictions, err := ActiveBans(storage.DB, user.ID)
+	if err != nil {
+		return ProfileResponse{}, err
+	}
+	return ProfileResponse{User: user, SteamAPIKeySet: user.SteamAPIKey != "", Restrictions: restrictions}, nil
 }
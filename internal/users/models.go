@@ -1,6 +1,18 @@
 package users
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Роли пользователей
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+	RoleSupport   = "support"
+)
 
 type User struct {
 	gorm.Model
@@ -8,4 +20,59 @@ type User struct {
 	Username  string
 	AvatarURL string
 	SteamLVL  int
+	Role      string `gorm:"not null;default:user"`
+	// TradeURL - ссылка на обмен, без неё нельзя продавать и покупать
+	TradeURL string
+	// SteamAPIKey - ключ Steam Web API продавца, по истории его обменов проверяется передача предметов в P2P-заказах.
+	// Хранится зашифрованным и не отдаётся в API
+	SteamAPIKey EncryptedString `json:"-"`
+
+	// Данные проверки аккаунта Steam, обновляются при каждом входе
+	VACBanned           bool
+	NumberOfVACBans     int
+	NumberOfGameBans    int
+	CommunityBanned     bool
+	EconomyBan          string
+	DaysSinceLastBan    int
+	CommunityVisibility int
+	InventoryPrivate    bool
+	SteamCreatedAt      *time.Time
+	TrustCheckedAt      *time.Time
+}
+
+// Области действия блокировок
+const (
+	BanScopeLogin       = "login"
+	BanScopeTrading     = "trading"
+	BanScopeWithdrawals = "withdrawals"
+)
+
+// Ban - ограничение пользователя. Без ExpiresAt действует бессрочно, снятая блокировка хранит LiftedAt
+type Ban struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Scope     string     `json:"scope" gorm:"not null;index"`
+	Reason    string     `json:"reason" gorm:"not null"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// IssuedBy - ID выдавшего администратора, 0 у блокировок по автоматической проверке аккаунта
+	IssuedBy  uint       `json:"issued_by"`
+	LiftedAt  *time.Time `json:"lifted_at,omitempty"`
+	LiftedBy  *uint      `json:"lifted_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type TradeURLRequest struct {
+	TradeURL string `json:"trade_url" binding:"required"`
+}
+
+type SteamAPIKeyRequest struct {
+	APIKey string `json:"api_key" binding:"required"`
+}
+
+type ProfileResponse struct {
+	User
+	// SteamAPIKeySet - ключ Steam Web API сохранён, и можно продавать без торговых ботов
+	SteamAPIKeySet bool `json:"steam_api_key_set"`
+	// Restrictions - действующие ограничения аккаунта
+	Restrictions []Ban `json:"restrictions"`
 }
@@ -0,0 +1,52 @@
+package users
+
+import (
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// roleRanks - старшинство ролей. Действия над пользователем доступны только сотруднику с ролью выше его роли
+var roleRanks = map[string]int{
+	RoleUser:      0,
+	RoleSupport:   1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// RoleRank возвращает старшинство роли, у неизвестной роли оно как у обычного пользователя
+func RoleRank(role string) int {
+	return roleRanks[role]
+}
+
+// AdminSteamIDs - SteamID администраторов из ADMIN_STEAM_IDS через запятую.
+// Роль admin выдаётся только так, остальные роли назначает администратор через /admin/users/{id}/role
+func AdminSteamIDs() []string {
+	var ids []string
+	for _, id := range strings.Split(os.Getenv("ADMIN_STEAM_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// InitialRole - роль нового пользователя
+func InitialRole(steamID string) string {
+	for _, id := range AdminSteamIDs() {
+		if id == steamID {
+			return RoleAdmin
+		}
+	}
+	return RoleUser
+}
+
+// SeedAdmins выдаёт роль admin уже зарегистрированным пользователям из ADMIN_STEAM_IDS
+func SeedAdmins(db *gorm.DB) error {
+	ids := AdminSteamIDs()
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.Model(&User{}).Where("steam_id IN ? AND role <> ?", ids, RoleAdmin).Update("role", RoleAdmin).Error
+}
@@ -0,0 +1,131 @@
+package users
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// secretPrefix отмечает зашифрованные значения, значения без него сохранены до шифрования
+const secretPrefix = "enc:v1:"
+
+var secretKey []byte
+
+// InitSecrets загружает ключ шифрования секретов пользователей из SECRETS_ENCRYPTION_KEY -
+// 32 байта в base64. Без ключа ключи Steam Web API пришлось бы хранить открыто, поэтому сервер не запускается
+func InitSecrets() {
+	encoded := os.Getenv("SECRETS_ENCRYPTION_KEY")
+	if encoded == "" {
+		log.Fatal("Не задан SECRETS_ENCRYPTION_KEY")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Fatal("SECRETS_ENCRYPTION_KEY не в base64: ", err)
+	}
+	if err := SetSecretKey(key); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// SetSecretKey задаёт ключ AES-256 для EncryptedString
+func SetSecretKey(key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("ключ шифрования должен быть 32 байта, получено %d", len(key))
+	}
+	secretKey = key
+	return nil
+}
+
+// EncryptedString - строка, которая хранится в базе зашифрованной AES-GCM и расшифровывается при чтении
+type EncryptedString string
+
+func secretCipher() (cipher.AEAD, error) {
+	if secretKey == nil {
+		return nil, errors.New("ключ шифрования секретов не задан")
+	}
+	block, err := aes.NewCipher(secretKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	gcm, err := secretCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(s), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *EncryptedString) Scan(value interface{}) error {
+	var stored string
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("неподдерживаемый тип секрета %T", value)
+	}
+
+	if !strings.HasPrefix(stored, secretPrefix) {
+		// Сохранено до шифрования, зашифруется в EncryptLegacySecrets
+		*s = EncryptedString(stored)
+		return nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, secretPrefix))
+	if err != nil {
+		return err
+	}
+	gcm, err := secretCipher()
+	if err != nil {
+		return err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return errors.New("повреждённый секрет")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return fmt.Errorf("не удалось расшифровать секрет: %w", err)
+	}
+	*s = EncryptedString(plain)
+	return nil
+}
+
+// EncryptLegacySecrets шифрует ключи Steam Web API, сохранённые открыто до появления шифрования
+func EncryptLegacySecrets(db *gorm.DB) error {
+	var legacy []User
+	err := db.Select("id", "steam_api_key").
+		Where("steam_api_key <> '' AND steam_api_key NOT LIKE ?", secretPrefix+"%").
+		Find(&legacy).Error
+	if err != nil {
+		return err
+	}
+	for _, user := range legacy {
+		if err := db.Model(&User{}).Where("id = ?", user.ID).Update("steam_api_key", user.SteamAPIKey).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package users
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// steamID64Base - SteamID64 аккаунта с нулевым accountid, partner в ссылке на обмен - смещение от него
+const steamID64Base = 76561197960265728
+
+var (
+	ErrInvalidTradeURL    = errors.New("некорректная ссылка на обмен")
+	ErrTradeURLWrongOwner = errors.New("ссылка на обмен принадлежит другому аккаунту")
+	tradeTokenPattern     = regexp.MustCompile(`^[A-Za-z0-9_-]{8}$`)
+	tradeOfferHosts       = map[string]bool{"steamcommunity.com": true, "www.steamcommunity.com": true}
+)
+
+// TradeURLParts разбирает ссылку на обмен вида https://steamcommunity.com/tradeoffer/new/?partner=...&token=...
+// и возвращает SteamID64 её владельца и токен
+func TradeURLParts(raw string) (string, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || !tradeOfferHosts[u.Host] {
+		return "", "", ErrInvalidTradeURL
+	}
+	if u.Path != "/tradeoffer/new/" && u.Path != "/tradeoffer/new" {
+		return "", "", ErrInvalidTradeURL
+	}
+
+	query := u.Query()
+	partner, err := strconv.ParseUint(query.Get("partner"), 10, 32)
+	if err != nil || partner == 0 {
+		return "", "", ErrInvalidTradeURL
+	}
+	token := query.Get("token")
+	if !tradeTokenPattern.MatchString(token) {
+		return "", "", ErrInvalidTradeURL
+	}
+	return strconv.FormatUint(partner+steamID64Base, 10), token, nil
+}
+
+// ParseTradeURL проверяет ссылку на обмен и то, что она принадлежит steamID.
+// Возвращает ссылку в каноническом виде
+func ParseTradeURL(raw, steamID string) (string, error) {
+	owner, token, err := TradeURLParts(raw)
+	if err != nil {
+		return "", err
+	}
+	if owner != steamID {
+		return "", ErrTradeURLWrongOwner
+	}
+
+	partner, _ := strconv.ParseUint(owner, 10, 64)
+	canonical := url.Values{"partner": {strconv.FormatUint(partner-steamID64Base, 10)}, "token": {token}}
+	return "https://steamcommunity.com/tradeoffer/new/?" + canonical.Encode(), nil
+}
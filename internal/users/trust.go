@@ -0,0 +1,151 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Правила проверки аккаунта Steam
+const (
+	TrustRuleEconomyBan       = "economy_ban"
+	TrustRuleVACBan           = "vac_ban"
+	TrustRuleCommunityBan     = "community_ban"
+	TrustRulePrivateProfile   = "private_profile"
+	TrustRulePrivateInventory = "private_inventory"
+	TrustRuleAccountAge       = "account_age"
+)
+
+// Действия при нарушении правила
+const (
+	TrustActionOff      = "off"
+	TrustActionRestrict = "restrict"
+	TrustActionBlock    = "block"
+)
+
+const (
+	// communityVisibilityPublic - публичный профиль Steam
+	communityVisibilityPublic = 3
+	defaultMinAccountAgeDays  = 30
+)
+
+var defaultTrustRules = map[string]string{
+	TrustRuleEconomyBan:       TrustActionRestrict,
+	TrustRuleVACBan:           TrustActionOff,
+	TrustRuleCommunityBan:     TrustActionOff,
+	TrustRulePrivateProfile:   TrustActionOff,
+	TrustRulePrivateInventory: TrustActionRestrict,
+	TrustRuleAccountAge:       TrustActionOff,
+}
+
+// TrustViolation - нарушенное правило проверки аккаунта
+type TrustViolation struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// TrustRules - действия по правилам. Настраиваются переменной TRUST_RULES
+// в формате "economy_ban=block,private_inventory=restrict", не указанные правила берутся по умолчанию.
+// Минимальный возраст аккаунта задаётся TRUST_MIN_ACCOUNT_AGE_DAYS
+func TrustRules() map[string]string {
+	rules := make(map[string]string, len(defaultTrustRules))
+	for rule, action := range defaultTrustRules {
+		rules[rule] = action
+	}
+
+	for _, pair := range strings.Split(os.Getenv("TRUST_RULES"), ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		rule, action := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if _, ok := defaultTrustRules[rule]; !ok {
+			fmt.Println("Неизвестное правило проверки аккаунта:", rule)
+			continue
+		}
+		switch action {
+		case TrustActionOff, TrustActionRestrict, TrustActionBlock:
+			rules[rule] = action
+		default:
+			fmt.Println("Неизвестное действие правила проверки аккаунта:", action)
+		}
+	}
+	return rules
+}
+
+func minAccountAge() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRUST_MIN_ACCOUNT_AGE_DAYS"))
+	if err != nil || days < 0 {
+		days = defaultMinAccountAgeDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// CheckTrust проверяет сохранённые данные аккаунта Steam по правилам и возвращает нарушения
+func (u User) CheckTrust(rules map[string]string) []TrustViolation {
+	failed := map[string]string{}
+	if u.EconomyBan != "" && u.EconomyBan != "none" {
+		failed[TrustRuleEconomyBan] = "торговая блокировка Steam (" + u.EconomyBan + ")"
+	}
+	if u.VACBanned || u.NumberOfGameBans > 0 {
+		failed[TrustRuleVACBan] = "VAC или игровая блокировка Steam"
+	}
+	if u.CommunityBanned {
+		failed[TrustRuleCommunityBan] = "блокировка сообщества Steam"
+	}
+	if u.CommunityVisibility != communityVisibilityPublic {
+		failed[TrustRulePrivateProfile] = "закрытый профиль Steam"
+	}
+	if u.InventoryPrivate {
+		failed[TrustRulePrivateInventory] = "закрытый инвентарь Steam"
+	}
+	if u.SteamCreatedAt == nil || time.Since(*u.SteamCreatedAt) < minAccountAge() {
+		failed[TrustRuleAccountAge] = "аккаунт Steam слишком новый"
+	}
+
+	var violations []TrustViolation
+	for _, rule := range []string{TrustRuleEconomyBan, TrustRuleVACBan, TrustRuleCommunityBan, TrustRulePrivateProfile, TrustRulePrivateInventory, TrustRuleAccountAge} {
+		reason, ok := failed[rule]
+		if !ok || rules[rule] == TrustActionOff || rules[rule] == "" {
+			continue
+		}
+		violations = append(violations, TrustViolation{Rule: rule, Action: rules[rule], Reason: reason})
+	}
+	return violations
+}
+
+// ApplyTrustRestrictions выдаёт автоматическое ограничение торговли по нарушениям с действием restrict
+// и снимает его, если аккаунт снова проходит проверку. Блокировки, выданные администраторами, не затрагиваются
+func ApplyTrustRestrictions(db *gorm.DB, userID uint, violations []TrustViolation) error {
+	var reasons []string
+	for _, v := range violations {
+		if v.Action == TrustActionRestrict {
+			reasons = append(reasons, v.Reason)
+		}
+	}
+
+	automatic := db.Model(&Ban{}).Where("user_id = ? AND scope = ? AND issued_by = 0 AND lifted_at IS NULL", userID, BanScopeTrading)
+	if len(reasons) == 0 {
+		return automatic.Update("lifted_at", time.Now()).Error
+	}
+
+	reason := "Автоматическая проверка аккаунта: " + strings.Join(reasons, ", ")
+	var existing Ban
+	err := automatic.First(&existing).Error
+	if err == nil {
+		if existing.Reason == reason {
+			return nil
+		}
+		return db.Model(&existing).Update("reason", reason).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return db.Create(&Ban{UserID: userID, Scope: BanScopeTrading, Reason: reason}).Error
+}
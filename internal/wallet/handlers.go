@@ -0,0 +1,81 @@
+package wallet
+
+import (
+	"cs-market/internal/storage"
+	"cs-market/internal/users"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Security BearerAuth
+// GetWalletHandler godoc
+// @Summary Баланс кошелька
+// @Description Получение баланса внутреннего кошелька пользователя
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Success 200 {object} WalletResponse "Баланс"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения кошелька"
+// @Router /profile/wallet [get]
+func GetWalletHandler(c *gin.Context) {
+	var user users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	account, err := UserAccount(storage.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения кошелька"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WalletResponse{Balance: ToRubles(account.Balance), Currency: Currency})
+}
+
+// @Security BearerAuth
+// GetTransactionsHandler godoc
+// @Summary История операций кошелька
+// @Description Получение операций по кошельку пользователя, новые первыми
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param limit query int false "Количество операций (до 100)"
+// @Param offset query int false "Смещение"
+// @Success 200 {array} TransactionResponse "Операции"
+// @Failure 400 {object} response.ErrorResponse "Некорректные параметры"
+// @Failure 404 {object} response.ErrorResponse "Пользователь не найден"
+// @Failure 500 {object} response.ErrorResponse "Ошибка получения операций"
+// @Router /profile/wallet/transactions [get]
+func GetTransactionsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный limit"})
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный offset"})
+		return
+	}
+
+	var user users.User
+	if err := storage.DB.Where("steam_id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	result, err := UserTransactions(storage.DB, user.ID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения операций"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
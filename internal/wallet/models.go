@@ -0,0 +1,70 @@
+package wallet
+
+import "time"
+
+// Типы счетов. У каждого пользователя свой счёт, остальные счета системные
+const (
+	AccountUser        = "user"
+	AccountEscrow      = "escrow"
+	AccountFees        = "fees"
+	AccountExternal    = "external"
+	AccountAdjustments = "adjustments"
+)
+
+// Виды операций в журнале
+const (
+	KindDeposit      = "deposit"
+	KindPurchaseHold = "purchase_hold"
+	KindSaleCredit   = "sale_credit"
+	KindFee          = "fee"
+	KindWithdrawal   = "withdrawal"
+	KindRefund       = "refund"
+	KindAdjustment   = "adjustment"
+)
+
+// Account - счёт в журнале. Суммы хранятся в копейках
+type Account struct {
+	ID     uint   `gorm:"primaryKey"`
+	Code   string `gorm:"unique;not null"`
+	Type   string `gorm:"not null;index"`
+	UserID *uint  `gorm:"index"`
+	// Balance - сумма всех проводок по счёту пользователя, изменяется только внутри Post.
+	// У системных счетов не ведётся, их баланс возвращает SystemBalance
+	Balance       int64 `gorm:"not null;default:0"`
+	AllowNegative bool  `gorm:"not null;default:false"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Transaction - неизменяемая запись журнала, сумма её проводок всегда равна нулю
+type Transaction struct {
+	ID          uint   `gorm:"primaryKey"`
+	Kind        string `gorm:"not null;index"`
+	Reference   string `gorm:"index"`
+	Description string
+	Entries     []Entry
+	CreatedAt   time.Time
+}
+
+// Entry - проводка по одному счёту: положительная сумма зачисляет, отрицательная списывает
+type Entry struct {
+	ID            uint  `gorm:"primaryKey"`
+	TransactionID uint  `gorm:"not null;index"`
+	AccountID     uint  `gorm:"not null;index"`
+	Amount        int64 `gorm:"not null"`
+	CreatedAt     time.Time
+}
+
+type WalletResponse struct {
+	Balance  float64 `json:"balance"`
+	Currency string  `json:"currency"`
+}
+
+type TransactionResponse struct {
+	ID          uint      `json:"id"`
+	Kind        string    `json:"kind"`
+	Amount      float64   `json:"amount"`
+	Reference   string    `json:"reference"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
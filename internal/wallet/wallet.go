@@ -0,0 +1,296 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const Currency = "RUB"
+
+var (
+	ErrInsufficientFunds = errors.New("недостаточно средств")
+	ErrUnbalanced        = errors.New("сумма проводок не равна нулю")
+)
+
+// Leg - одна сторона операции
+type Leg struct {
+	AccountID uint
+	Amount    int64
+}
+
+// FromRubles переводит сумму в рублях в копейки
+func FromRubles(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// ToRubles переводит сумму в копейках в рубли
+func ToRubles(amount int64) float64 {
+	return float64(amount) / 100
+}
+
+// UserAccount возвращает счёт пользователя, создавая его при первом обращении
+func UserAccount(tx *gorm.DB, userID uint) (Account, error) {
+	return ensureAccount(tx, Account{
+		Code:   fmt.Sprintf("%s:%d", AccountUser, userID),
+		Type:   AccountUser,
+		UserID: &userID,
+	})
+}
+
+// SystemAccount возвращает системный счёт указанного типа
+func SystemAccount(tx *gorm.DB, accountType string) (Account, error) {
+	return ensureAccount(tx, Account{
+		Code: accountType,
+		Type: accountType,
+		// Внешний счёт отражает деньги за пределами площадки и уходит в минус при пополнениях,
+		// счёт корректировок - источник ручных начислений администраторов
+		AllowNegative: accountType == AccountExternal || accountType == AccountAdjustments,
+	})
+}
+
+func ensureAccount(tx *gorm.DB, account Account) (Account, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return account, err
+	}
+	var existing Account
+	err := tx.Where("code = ?", account.Code).First(&existing).Error
+	return existing, err
+}
+
+// Post записывает операцию в журнал. Должна вызываться внутри транзакции БД:
+// счета пользователей блокируются, чтобы параллельные операции не увели баланс в минус.
+// Системные счета (эскроу, комиссии) общие для всех сделок и не блокируются, их баланс считается по проводкам
+func Post(tx *gorm.DB, kind, reference, description string, legs ...Leg) error {
+	if len(legs) < 2 {
+		return ErrUnbalanced
+	}
+
+	deltas := make(map[uint]int64)
+	var sum int64
+	for _, leg := range legs {
+		deltas[leg.AccountID] += leg.Amount
+		sum += leg.Amount
+	}
+	if sum != 0 {
+		return ErrUnbalanced
+	}
+
+	ids := make([]uint, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+
+	var accounts []Account
+	if err := tx.Select("id", "type").Where("id IN ?", ids).Find(&accounts).Error; err != nil {
+		return err
+	}
+	if len(accounts) != len(ids) {
+		return fmt.Errorf("счёт не найден")
+	}
+	var userIDs []uint
+	for _, account := range accounts {
+		if account.Type == AccountUser {
+			userIDs = append(userIDs, account.ID)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	// Блокируем счета пользователей в одном порядке, чтобы избежать взаимоблокировок
+	var locked []Account
+	if len(userIDs) > 0 {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", userIDs).Order("id").Find(&locked).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, account := range locked {
+		if !account.AllowNegative && account.Balance+deltas[account.ID] < 0 {
+			return ErrInsufficientFunds
+		}
+	}
+
+	entry := Transaction{Kind: kind, Reference: reference, Description: description}
+	for _, leg := range legs {
+		entry.Entries = append(entry.Entries, Entry{AccountID: leg.AccountID, Amount: leg.Amount})
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return err
+	}
+
+	for _, id := range userIDs {
+		err := tx.Model(&Account{}).Where("id = ?", id).
+			Update("balance", gorm.Expr("balance + ?", deltas[id])).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SystemBalance считает баланс системного счёта по проводкам журнала
+func SystemBalance(db *gorm.DB, accountType string) (int64, error) {
+	account, err := SystemAccount(db, accountType)
+	if err != nil {
+		return 0, err
+	}
+	var balance int64
+	err = db.Model(&Entry{}).Where("account_id = ?", account.ID).Select("COALESCE(SUM(amount), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// transfer переводит сумму между счётом пользователя и системным счётом.
+// Положительная сумма зачисляется пользователю, отрицательная списывается с него
+func transfer(tx *gorm.DB, kind, reference, description string, userID uint, systemType string, amount int64) error {
+	user, err := UserAccount(tx, userID)
+	if err != nil {
+		return err
+	}
+	system, err := SystemAccount(tx, systemType)
+	if err != nil {
+		return err
+	}
+
+	return Post(tx, kind, reference, description,
+		Leg{AccountID: system.ID, Amount: -amount},
+		Leg{AccountID: user.ID, Amount: amount})
+}
+
+func checkAmount(amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("сумма должна быть положительной")
+	}
+	return nil
+}
+
+// Deposit зачисляет поступившие извне средства на счёт пользователя
+func Deposit(tx *gorm.DB, userID uint, amount int64, reference string) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	return transfer(tx, KindDeposit, reference, "Пополнение баланса", userID, AccountExternal, amount)
+}
+
+// Withdraw списывает средства пользователя для вывода с площадки
+func Withdraw(tx *gorm.DB, userID uint, amount int64, reference string) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	return transfer(tx, KindWithdrawal, reference, "Вывод средств", userID, AccountExternal, -amount)
+}
+
+// CancelWithdrawal возвращает пользователю средства вывода, который платёжная система не провела
+func CancelWithdrawal(tx *gorm.DB, userID uint, amount int64, reference string) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	return transfer(tx, KindRefund, reference, "Возврат средств неудавшегося вывода", userID, AccountExternal, amount)
+}
+
+// Posted проверяет, есть ли в журнале операция заданного вида с указанной ссылкой
+func Posted(tx *gorm.DB, kind, reference string) (bool, error) {
+	var count int64
+	err := tx.Model(&Transaction{}).Where("kind = ? AND reference = ?", kind, reference).Count(&count).Error
+	return count > 0, err
+}
+
+// Hold резервирует средства покупателя на счёте эскроу
+func Hold(tx *gorm.DB, userID uint, amount int64, reference string) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	return transfer(tx, KindPurchaseHold, reference, "Резервирование средств на покупку", userID, AccountEscrow, -amount)
+}
+
+// Refund возвращает зарезервированные средства покупателю
+func Refund(tx *gorm.DB, userID uint, amount int64, reference string) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	return transfer(tx, KindRefund, reference, "Возврат средств", userID, AccountEscrow, amount)
+}
+
+// Settle переводит зарезервированные средства продавцу за вычетом комиссии площадки
+func Settle(tx *gorm.DB, sellerID uint, amount, fee int64, reference string) error {
+	if err := checkAmount(amount); err != nil {
+		return err
+	}
+	if fee < 0 || fee > amount {
+		return fmt.Errorf("некорректная комиссия")
+	}
+	if amount > fee {
+		if err := transfer(tx, KindSaleCredit, reference, "Зачисление за продажу", sellerID, AccountEscrow, amount-fee); err != nil {
+			return err
+		}
+	}
+	if fee == 0 {
+		return nil
+	}
+
+	escrow, err := SystemAccount(tx, AccountEscrow)
+	if err != nil {
+		return err
+	}
+	fees, err := SystemAccount(tx, AccountFees)
+	if err != nil {
+		return err
+	}
+	return Post(tx, KindFee, reference, "Комиссия площадки",
+		Leg{AccountID: escrow.ID, Amount: -fee},
+		Leg{AccountID: fees.ID, Amount: fee})
+}
+
+// Adjust вручную изменяет баланс пользователя: положительная сумма начисляет, отрицательная списывает
+func Adjust(tx *gorm.DB, userID uint, amount int64, reference, reason string) error {
+	if amount == 0 {
+		return fmt.Errorf("сумма корректировки не может быть нулевой")
+	}
+	return transfer(tx, KindAdjustment, reference, reason, userID, AccountAdjustments, amount)
+}
+
+// UserTransactions возвращает операции по счёту пользователя, новые первыми
+func UserTransactions(db *gorm.DB, userID uint, limit, offset int) ([]TransactionResponse, error) {
+	account, err := UserAccount(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID          uint
+		Kind        string
+		Amount      int64
+		Reference   string
+		Description string
+		CreatedAt   time.Time
+	}
+	err = db.Table("entries").
+		Select("transactions.id, transactions.kind, entries.amount, transactions.reference, "+
+			"transactions.description, transactions.created_at").
+		Joins("JOIN transactions ON transactions.id = entries.transaction_id").
+		Where("entries.account_id = ?", account.ID).
+		Order("transactions.id DESC").
+		Limit(limit).Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]TransactionResponse, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, TransactionResponse{
+			ID:          row.ID,
+			Kind:        row.Kind,
+			Amount:      ToRubles(row.Amount),
+			Reference:   row.Reference,
+			Description: row.Description,
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return result, nil
+}
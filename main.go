@@ -2,10 +2,16 @@ package main
 
 import (
 	_ "cs-market/docs"
+	"cs-market/internal/admin"
 	"cs-market/internal/auth"
 	"cs-market/internal/inventory"
+	"cs-market/internal/listings"
+	"cs-market/internal/orders"
+	"cs-market/internal/payments"
 	"cs-market/internal/storage"
+	"cs-market/internal/tradebot"
 	"cs-market/internal/users"
+	"cs-market/internal/wallet"
 	"log"
 	"os"
 
@@ -34,13 +40,47 @@ func main() {
 	}
 
 	storage.ConnectDatabase()
+	users.InitSecrets()
 
-	inventory.StartPriceUpdater(storage.DB)
-
-	err := storage.DB.AutoMigrate(&users.User{}, &inventory.Skin{})
+	err := storage.DB.AutoMigrate(&users.User{}, &auth.RefreshToken{},
+		&inventory.Skin{}, &inventory.SkinPriceSnapshot{}, &inventory.SkinSourcePrice{},
+		&inventory.InventoryCache{},
+		&listings.Listing{},
+		&wallet.Account{}, &wallet.Transaction{}, &wallet.Entry{},
+		&orders.Order{},
+		&admin.AuditLog{},
+		&auth.AuthCode{},
+		&users.Ban{},
+		&tradebot.TradeOffer{},
+		&payments.Payment{})
 	if err != nil {
 		log.Fatal("Ошибка миграции: ", err)
 	}
+	if err := users.MigrateLegacyBans(storage.DB); err != nil {
+		log.Fatal("Ошибка переноса блокировок: ", err)
+	}
+	if err := users.EncryptLegacySecrets(storage.DB); err != nil {
+		log.Fatal("Ошибка шифрования ключей Steam Web API: ", err)
+	}
+	if err := users.SeedAdmins(storage.DB); err != nil {
+		log.Fatal("Ошибка назначения администраторов: ", err)
+	}
+	if err := listings.MigrateSearchIndex(storage.DB); err != nil {
+		log.Fatal("Ошибка создания индекса поиска: ", err)
+	}
+
+	inventory.StartPriceUpdater(storage.DB)
+	orders.StartOrderExpirer(storage.DB)
+	orders.StartP2PVerifier(storage.DB)
+	orders.StartProtectionMonitor(storage.DB)
+	if err := tradebot.Init(storage.DB, orders.HandleTradeOffer); err != nil {
+		log.Fatal("Ошибка запуска торговых ботов: ", err)
+	}
+
+	if err := payments.Init(); err != nil {
+		log.Fatal("Ошибка настройки платёжной системы: ", err)
+	}
+	payments.StartReconciler(storage.DB)
 
 	auth.InitAuth()
 
@@ -55,18 +95,67 @@ func main() {
 	}))
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/.well-known/jwks.json", auth.JWKSHandler)
 	r.GET("/auth/steam", auth.SteamLoginHandler)
 	r.GET("/auth/steam/callback", auth.SteamCallbackHandler)
+	r.POST("/auth/exchange", auth.ExchangeCodeHandler)
 	r.POST("/auth/refresh", auth.RefreshTokenHandler)
+	r.POST("/auth/logout", auth.LogoutHandler)
+	r.POST("/auth/logout-all", auth.AuthMiddleware(), auth.LogoutAllHandler)
 	r.GET("/auth/verify", auth.AuthMiddleware(), auth.VerifyTokenHandler)
+	r.GET("/market", listings.GetMarketHandler)
+	r.GET("/skins/:market_hash_name/history", inventory.GetPriceHistoryHandler)
+	r.POST("/webhooks/payments/:provider", payments.WebhookHandler)
+
+	trading := auth.RequireNotRestricted(users.BanScopeTrading)
 
 	authorized := r.Group("/")
 	{
-		authorized.Use(auth.AuthMiddleware())
+		authorized.Use(auth.AuthMiddleware(), auth.RequireNotRestricted(users.BanScopeLogin))
 		authorized.GET("/authMud", auth.TokenProv)
 		authorized.GET("/profile", users.GetUserProfileHandler)
+		authorized.PUT("/profile/trade-url", users.UpdateTradeURLHandler)
+		authorized.PUT("/profile/steam-api-key", users.UpdateSteamAPIKeyHandler)
 		authorized.GET("/profile/inventory", inventory.GetMyInventoryHandler)
+		authorized.GET("/profile/sessions", auth.GetSessionsHandler)
+		authorized.DELETE("/profile/sessions/:id", auth.RevokeSessionHandler)
+		authorized.GET("/profile/wallet", wallet.GetWalletHandler)
+		authorized.GET("/profile/wallet/transactions", wallet.GetTransactionsHandler)
+		authorized.GET("/profile/wallet/payments", payments.GetPaymentsHandler)
+		authorized.POST("/wallet/deposit", payments.DepositHandler)
+		authorized.POST("/wallet/withdraw", auth.RequireNotRestricted(users.BanScopeWithdrawals), payments.WithdrawHandler)
+		authorized.GET("/profile/listings", listings.GetMyListingsHandler)
+		authorized.POST("/listings", trading, listings.CreateListingHandler)
+		authorized.PUT("/listings/:id/price", trading, listings.UpdateListingPriceHandler)
+		authorized.DELETE("/listings/:id", listings.CancelListingHandler)
+		authorized.POST("/market/listings/:id/buy", trading, orders.BuyListingHandler)
+		authorized.GET("/profile/orders", orders.GetMyOrdersHandler)
+		authorized.GET("/profile/orders/:id", orders.GetOrderHandler)
+		authorized.POST("/profile/orders/:id/trade-sent", orders.MarkTradeSentHandler)
+		authorized.POST("/profile/orders/:id/confirm", orders.ConfirmOrderHandler)
+		authorized.POST("/profile/orders/:id/cancel", orders.CancelOrderHandler)
 	}
+
+	staff := r.Group("/admin")
+	{
+		staff.Use(auth.AuthMiddleware(), auth.RequireNotRestricted(users.BanScopeLogin), auth.RequireRole(users.RoleAdmin, users.RoleModerator, users.RoleSupport))
+		staff.GET("/users", admin.ListUsersHandler)
+		staff.GET("/users/:id", admin.GetUserHandler)
+		staff.GET("/users/:id/bans", admin.GetUserBansHandler)
+		staff.GET("/users/:id/orders", admin.GetUserOrdersHandler)
+		staff.GET("/users/:id/wallet", admin.GetUserWalletHandler)
+		staff.GET("/audit", admin.GetAuditLogHandler)
+
+		moderation := staff.Group("/", auth.RequireRole(users.RoleAdmin, users.RoleModerator))
+		moderation.POST("/users/:id/ban", admin.BanUserHandler)
+		moderation.POST("/users/:id/unban", admin.UnbanUserHandler)
+		moderation.POST("/users/:id/logout", admin.ForceLogoutHandler)
+		moderation.POST("/orders/:id/dispute", admin.DisputeOrderHandler)
+
+		staff.POST("/users/:id/wallet/adjust", auth.RequireRole(users.RoleAdmin), admin.AdjustBalanceHandler)
+		staff.PUT("/users/:id/role", auth.RequireRole(users.RoleAdmin), admin.SetRoleHandler)
+	}
+
 	if err := r.Run(":8080"); err != nil {
 		log.Fatal("Ошибка запуска сервера:", err)
 	}